@@ -0,0 +1,6 @@
+#include <stdio.h>
+
+int main(int argc, char **argv) {
+    printf("built with the supported flags only\n");
+    return 0;
+}
@@ -0,0 +1,27 @@
+#include <stdio.h>
+
+#include "config.h"
+
+#if CONFIG_HAVE_UNISTD_H
+#include <unistd.h>
+#endif // CONFIG_HAVE_UNISTD_H
+
+int main() {
+    printf("unistd.h: %d\n", CONFIG_HAVE_UNISTD_H);
+    printf("does_not_exist.h: %d\n", CONFIG_HAVE_DOES_NOT_EXIST_H);
+    printf("sizeof(void *): %d\n", CONFIG_SIZEOF_VOID_P);
+
+#ifdef HAVE_COS
+    printf("cos: found\n");
+#endif // HAVE_COS
+
+#ifdef SIZEOF_LONG_LONG
+    printf("sizeof(long long): %d\n", SIZEOF_LONG_LONG);
+#endif // SIZEOF_LONG_LONG
+
+#ifdef HAVE_BUILTIN_EXPECT
+    printf("__builtin_expect: found\n");
+#endif // HAVE_BUILTIN_EXPECT
+
+    return 0;
+}
@@ -0,0 +1,6 @@
+#include <stdio.h>
+
+int main(void) {
+    printf("Built with a compiler of a user-defined family!\n");
+    return 0;
+}
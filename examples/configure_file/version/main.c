@@ -37,5 +37,7 @@ int main() {
     // no abort because not mandatory?
 #endif // MY_VERSION_TWEAK
 
+    printf("compiler: %s (%s)\n", MY_COMPILER, MY_COMPILER_TARGET);
+
     return 0;
 }
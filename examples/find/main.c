@@ -1,3 +1,8 @@
+#include <stdio.h>
+#include "paths.h"
+
 int main() {
+    printf("python3: %s\n", PYTHON3_PATH);
+    printf("gopls: %s\n", GOPLS_PATH);
     return 0;
 }
@@ -0,0 +1,7 @@
+#include <stdio.h>
+#include <greeting/greeting.h>
+
+int main() {
+    printf("%s\n", GREETING);
+    return 0;
+}
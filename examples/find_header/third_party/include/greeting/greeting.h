@@ -0,0 +1,8 @@
+// header outside of the system include directories
+
+#ifndef _GREETING_H_
+#define _GREETING_H_
+
+#define GREETING "Hello from a found header!"
+
+#endif // _GREETING_H_
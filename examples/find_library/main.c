@@ -0,0 +1,9 @@
+#include <math.h>
+#include <stdio.h>
+#include <zlib.h>
+
+int main() {
+    printf("sqrt(2) = %f\n", sqrt(2.0));
+    printf("zlib version: %s\n", zlibVersion());
+    return 0;
+}
@@ -0,0 +1,29 @@
+#!/bin/sh
+# Generates a lookup table of squares: generate_table.sh <output dir> <count>
+
+set -e
+
+OUTPUT_DIR=$1
+COUNT=$2
+
+cat > ${OUTPUT_DIR}/squares.h <<HEADER
+#ifndef _SQUARES_H_
+#define _SQUARES_H_
+
+#define SQUARES_COUNT ${COUNT}
+
+extern const int squares[SQUARES_COUNT];
+
+#endif // _SQUARES_H_
+HEADER
+
+echo '#include "squares.h"' > ${OUTPUT_DIR}/squares.c
+echo 'const int squares[SQUARES_COUNT] = {' >> ${OUTPUT_DIR}/squares.c
+
+i=0
+while [ ${i} -lt ${COUNT} ]; do
+    echo "    $((i * i))," >> ${OUTPUT_DIR}/squares.c
+    i=$((i + 1))
+done
+
+echo '};' >> ${OUTPUT_DIR}/squares.c
@@ -0,0 +1,10 @@
+#include <stdio.h>
+#include <squares.h>
+
+int main() {
+    for (int i = 0; i < SQUARES_COUNT; i++) {
+        printf("%d squared is %d\n", i, squares[i]);
+    }
+
+    return 0;
+}
@@ -0,0 +1,8 @@
+#include <stdio.h>
+
+#include "square.h"
+
+int main(void) {
+    printf("square(7) = %d\n", square(7));
+    return 0;
+}
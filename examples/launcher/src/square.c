@@ -0,0 +1,5 @@
+#include "square.h"
+
+int square(int value) {
+    return value * value;
+}
@@ -0,0 +1,11 @@
+#!/bin/sh
+# usage: timing.sh <step> <command...>
+step=$1
+shift
+
+start=$(date +%s%N)
+"$@"
+status=$?
+
+echo "[timing] $step took $(( ($(date +%s%N) - start) / 1000000 ))ms"
+exit $status
@@ -1,3 +0,0 @@
-#!/bin/bash
-
-LD_LIBRARY_PATH=../mylib/build ./build/linked-with-mylib
@@ -0,0 +1,7 @@
+#ifndef GREETING_H
+#define GREETING_H
+
+const char *greeting(void);
+int answer(void);
+
+#endif
@@ -0,0 +1,10 @@
+#include <iostream>
+
+extern "C" {
+#include "greeting.h"
+}
+
+int main() {
+    std::cout << greeting() << " (" << answer() << ")" << std::endl;
+    return 0;
+}
@@ -0,0 +1,10 @@
+#ifndef GREETING_H
+#define GREETING_H
+
+#ifdef FROM_MSVC
+#define GREETING "Hello from MSVC!"
+#else
+#define GREETING "Hello!"
+#endif
+
+#endif
@@ -0,0 +1,10 @@
+#ifndef PCH_H
+#define PCH_H
+
+#include <algorithm>
+#include <iostream>
+#include <map>
+#include <string>
+#include <vector>
+
+#endif
@@ -0,0 +1,6 @@
+#ifndef WORDS_H
+#define WORDS_H
+
+std::map<std::string, int> count_words(const std::vector<std::string> &words);
+
+#endif
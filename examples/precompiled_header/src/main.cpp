@@ -0,0 +1,12 @@
+#include "words.h"
+
+int main() {
+    std::vector<std::string> words = {"pch", "gmakec", "pch"};
+    std::sort(words.begin(), words.end());
+
+    for (const auto &[word, count] : count_words(words)) {
+        std::cout << word << ": " << count << std::endl;
+    }
+
+    return 0;
+}
@@ -0,0 +1,15 @@
+#include <stdio.h>
+
+int main() {
+#ifdef NDEBUG
+    printf("assertions: disabled\n");
+#else
+    printf("assertions: enabled\n");
+#endif // NDEBUG
+
+#ifdef PROFILING
+    printf("profiling: enabled\n");
+#endif // PROFILING
+
+    return 0;
+}
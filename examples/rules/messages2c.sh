@@ -0,0 +1,18 @@
+#!/bin/sh
+# Generates C strings from NAME=text lines: messages2c.sh <input> <output.c> <output.h>
+
+set -e
+
+INPUT=$1
+OUTPUT_C=$2
+OUTPUT_H=$3
+HEADER=$(basename ${OUTPUT_H})
+
+echo "// generated from ${INPUT}" > ${OUTPUT_H}
+echo "#include \"${HEADER}\"" > ${OUTPUT_C}
+
+while IFS='=' read -r name text; do
+    [ -z "${name}" ] && continue
+    echo "extern const char *${name};" >> ${OUTPUT_H}
+    echo "const char *${name} = \"${text}\";" >> ${OUTPUT_C}
+done < ${INPUT}
@@ -0,0 +1,8 @@
+#include <stdio.h>
+#include <greetings.h>
+
+int main() {
+    printf("%s\n", MESSAGE_HELLO);
+    printf("%s\n", MESSAGE_BYE);
+    return 0;
+}
@@ -0,0 +1,6 @@
+#ifndef _ADD_H_
+#define _ADD_H_
+
+int add(int a, int b);
+
+#endif // _ADD_H_
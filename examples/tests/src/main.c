@@ -0,0 +1,16 @@
+#include <stdio.h>
+#include <stdlib.h>
+#include "add.h"
+
+int main(int argc, char **argv) {
+    int sum = 0;
+
+    for (int i = 1; i < argc; i++) {
+        sum = add(sum, atoi(argv[i]));
+    }
+
+    printf("%d\n", sum);
+
+    const char *expected = getenv("EXPECTED_SUM");
+    return expected != NULL && atoi(expected) != sum;
+}
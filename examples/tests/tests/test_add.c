@@ -0,0 +1,11 @@
+#include <stdio.h>
+#include "add.h"
+
+int main() {
+    if (add(2, 2) != 4) {
+        fprintf(stderr, "add(2, 2) != 4\n");
+        return 1;
+    }
+
+    return 0;
+}
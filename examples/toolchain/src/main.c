@@ -0,0 +1,8 @@
+#include <stdio.h>
+
+#include "toolchain.h"
+
+int main(void) {
+    printf("Built for %s with %s\n", TARGET_PLATFORM, COMPILER_NAME);
+    return 0;
+}
@@ -0,0 +1,9 @@
+#include <stdio.h>
+
+#include "shapes.h"
+
+int main(void) {
+    printf("circle: %.2f\n", circle_area(1.0));
+    printf("square: %.2f\n", square_area(2.0));
+    return 0;
+}
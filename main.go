@@ -1,9 +1,11 @@
 package main
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"runtime"
 
 	"github.com/urfave/cli/v2"
 
@@ -33,9 +35,7 @@ func collectDefinitionContexts(defContext *gmakec.DefinitionContext) error {
 	return nil
 }
 
-func configure(context *cli.Context) error {
-	targets := context.Args().Slice()
-
+func loadDefinitionContexts() error {
 	definitionContexts = make([]*gmakec.DefinitionContext, 0)
 	defContext, err := gmakec.NewDefinitionContext(GLOBAL_DEFINITION_YAML)
 
@@ -43,10 +43,18 @@ func configure(context *cli.Context) error {
 		return err
 	}
 
-	err = collectDefinitionContexts(defContext)
+	if err = collectDefinitionContexts(defContext); err != nil {
+		return err
+	}
+
+	return defContext.SanitizeProfiles(definitionContexts)
+}
+
+func configureTargets(targets ...string) error {
+	err := loadDefinitionContexts()
 
 	if err != nil {
-		return nil
+		return err
 	}
 
 	for _, dc := range definitionContexts {
@@ -60,26 +68,193 @@ func configure(context *cli.Context) error {
 	return nil
 }
 
-func build(context *cli.Context) error {
-	err := configure(context)
+func configure(context *cli.Context) error {
+	return configureTargets(context.Args().Slice()...)
+}
+
+func buildTargets(verbose bool, targets ...string) error {
+	err := configureTargets(targets...)
 
 	if err != nil {
 		return err
 	}
 
+	for _, dc := range definitionContexts {
+		err = dc.Build(verbose)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func build(context *cli.Context) error {
+	if context.Bool("dry-run") {
+		return dryRun(context.Args().Slice()...)
+	}
+
 	verbose := context.Args().Get(0) == "verbose"
+	return buildTargets(verbose, context.Args().Slice()...)
+}
+
+func dryRun(targets ...string) error {
+	if err := configureTargets(targets...); err != nil {
+		return err
+	}
 
 	for _, dc := range definitionContexts {
-		err = dc.Build(verbose)
+		if err := dc.DryRun(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func test(context *cli.Context) error {
+	err := buildTargets(false)
+
+	if err != nil {
+		return err
+	}
+
+	filter := context.Args().Get(0)
+	testCases := []gmakec.TestCase{}
+
+	for _, dc := range definitionContexts {
+		dcTestCases, err := dc.TestCases(filter, &definitionContexts)
 
 		if err != nil {
 			return err
 		}
+
+		testCases = append(testCases, dcTestCases...)
+	}
+
+	if len(testCases) == 0 {
+		log.Printf("WARNING: No tests found matching filter `%s`\n", filter)
+		return nil
+	}
+
+	results := gmakec.RunTests(testCases, context.Int("jobs"))
+
+	if junitPath := context.String("junit"); len(junitPath) > 0 {
+		rootContext := definitionContexts[len(definitionContexts)-1]
+		err = gmakec.WriteJUnitReport(junitPath, rootContext.ProjectName(), results)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return gmakec.SummarizeTests(results)
+}
+
+func run(context *cli.Context) error {
+	targetName := context.Args().First()
+
+	if len(targetName) == 0 {
+		return fmt.Errorf("Usage: gmakec run <target> [-- args...]")
+	}
+
+	err := loadDefinitionContexts()
+
+	if err != nil {
+		return err
+	}
+
+	// dependencies might be defined in imports, which are configured separately
+	targets, err := gmakec.TargetDependencies(targetName, &definitionContexts)
+
+	if err != nil {
+		return err
+	}
+
+	err = buildTargets(false, targets...)
+
+	if err != nil {
+		return err
+	}
+
+	args := context.Args().Tail()
+
+	// the separator of `gmakec run <target> -- args...` is not passed on
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+
+	exitCode, err := gmakec.RunTarget(targetName, args, &definitionContexts)
+
+	if err != nil {
+		return err
+	}
+
+	if exitCode != 0 {
+		return cli.Exit("", exitCode)
 	}
 
 	return nil
 }
 
+func installProject(installer *gmakec.Installer) error {
+	err := buildTargets(false)
+
+	if err != nil {
+		return err
+	}
+
+	for _, dc := range definitionContexts {
+		err = dc.Install(installer, &definitionContexts)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func install(context *cli.Context) error {
+	return installProject(&gmakec.Installer{
+		Prefix:  context.String("prefix"),
+		DestDir: context.String("destdir"),
+	})
+}
+
+func packageProject(context *cli.Context) error {
+	stagingDir, err := os.MkdirTemp("", "gmakec-package-")
+
+	if err != nil {
+		return err
+	}
+
+	defer gmakec.RemovePath(stagingDir)
+
+	err = installProject(&gmakec.Installer{
+		Prefix:  context.String("prefix"),
+		DestDir: stagingDir,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	rootContext := definitionContexts[len(definitionContexts)-1]
+
+	packager := &gmakec.Packager{
+		Name:       rootContext.ProjectName(),
+		Version:    rootContext.Definition.Version,
+		Formats:    context.StringSlice("format"),
+		Strip:      context.Bool("strip"),
+		StagingDir: stagingDir,
+		OutputDir:  context.String("output"),
+	}
+
+	return packager.Package()
+}
+
 func clean(context *cli.Context) error {
 	defContext, err := gmakec.NewDefinitionContext(GLOBAL_DEFINITION_YAML)
 
@@ -93,13 +268,13 @@ func clean(context *cli.Context) error {
 		return nil
 	}
 
-	for _, dc := range definitionContexts {
-		for _, targetDef := range dc.Definition.Targets {
-			outputDir := filepath.Join(dc.DefinitionPath, filepath.Dir(targetDef.Output))
-			gmakec.RemovePath(outputDir)
-		}
+	// outputs are inside the directory of the profile
+	if err = defContext.SanitizeProfiles(definitionContexts); err != nil {
+		return err
+	}
 
-		gmakec.RemovePath(dc.ConfigureDir)
+	for _, dc := range definitionContexts {
+		dc.Clean()
 	}
 
 	return nil
@@ -132,6 +307,54 @@ func reconfigure(context *cli.Context) error {
 func main() {
 	app := &cli.App{
 		DefaultCommand: "build",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "unity",
+				Usage: "enable or disable (--unity=false) unity builds for all targets",
+			},
+			&cli.StringFlag{
+				Name:  "launcher",
+				Usage: "prefix compile steps with the given command, e.g. ccache",
+			},
+			&cli.StringFlag{
+				Name:  "link-launcher",
+				Usage: "prefix link steps with the given command",
+			},
+			&cli.StringFlag{
+				Name:  "toolchain",
+				Usage: "cross compile with the toolchain definition of the given file",
+			},
+			&cli.StringFlag{
+				Name:  "profile",
+				Usage: "build with the given profile, e.g. debug, release, relwithdebinfo, minsizerel",
+			},
+		},
+		Before: func(context *cli.Context) error {
+			if context.IsSet("unity") {
+				gmakec.SetUnityBuilds(context.Bool("unity"))
+			}
+
+			if context.IsSet("launcher") {
+				gmakec.SetLauncher(context.String("launcher"))
+			}
+
+			if context.IsSet("link-launcher") {
+				gmakec.SetLinkLauncher(context.String("link-launcher"))
+			}
+
+			// kept from the last configure run unless selected again
+			if context.IsSet("profile") {
+				gmakec.SetProfile(context.String("profile"))
+			} else {
+				gmakec.LoadProfile(gmakec.CONFIGURE_DIR)
+			}
+
+			if context.IsSet("toolchain") {
+				return gmakec.LoadToolchain(context.String("toolchain"))
+			}
+
+			return nil
+		},
 		Commands: []*cli.Command{
 			{
 				Name:   "configure",
@@ -142,6 +365,76 @@ func main() {
 				Name:   "build",
 				Usage:  "build the project",
 				Action: build,
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "print the commands which would be run instead of running them",
+					},
+				},
+			},
+			{
+				Name:      "test",
+				Usage:     "build the project and run its tests",
+				ArgsUsage: "[filter]",
+				Action:    test,
+				Flags: []cli.Flag{
+					&cli.IntFlag{
+						Name:  "jobs",
+						Usage: "number of tests to run in parallel",
+						Value: runtime.NumCPU(),
+					},
+					&cli.StringFlag{
+						Name:  "junit",
+						Usage: "write a JUnit XML report to the given path",
+					},
+				},
+			},
+			{
+				Name:      "run",
+				Usage:     "build a target and run its output",
+				ArgsUsage: "<target> [-- args...]",
+				Action:    run,
+			},
+			{
+				Name:   "install",
+				Usage:  "build and install the project",
+				Action: install,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "prefix",
+						Usage: "installation prefix",
+						Value: "/usr/local",
+					},
+					&cli.StringFlag{
+						Name:  "destdir",
+						Usage: "staging directory prepended to the prefix, e.g. for packaging",
+					},
+				},
+			},
+			{
+				Name:   "package",
+				Usage:  "build, install into a staging directory and create distributable archives",
+				Action: packageProject,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "prefix",
+						Usage: "installation prefix inside the archives",
+					},
+					&cli.StringSliceFlag{
+						Name:  "format",
+						Usage: "archive formats to create: tar.gz, zip",
+						Value: cli.NewStringSlice("tar.gz"),
+					},
+					&cli.BoolFlag{
+						Name:  "strip",
+						Usage: "strip binaries before packaging",
+					},
+					&cli.StringFlag{
+						Name:  "output",
+						Usage: "directory to write the archives to",
+						Value: "package",
+					},
+				},
 			},
 			{
 				Name:   "clean",
@@ -20,7 +20,7 @@ type ActionDefinition struct {
 	Output      ActionOutputDefinition `yaml:"output"`
 }
 
-func (this *ActionDefinition) commandWithShell(shellString string) *exec.Cmd {
+func (this *ActionDefinition) commandWithShell(shellString string, commandString string) *exec.Cmd {
 	commandPrefix := strings.Split(shellString, " ")
 	command := exec.Command(commandPrefix[0])
 
@@ -28,12 +28,12 @@ func (this *ActionDefinition) commandWithShell(shellString string) *exec.Cmd {
 		command.Args = append(command.Args, commandPrefix[1])
 	}
 
-	command.Args = append(command.Args, this.Command)
+	command.Args = append(command.Args, commandString)
 	return command
 }
 
-func (this *ActionDefinition) commandWithoutShell() *exec.Cmd {
-	args := strings.Split(this.Command, " ")
+func (this *ActionDefinition) commandWithoutShell(commandString string) *exec.Cmd {
+	args := strings.Split(commandString, " ")
 	command := exec.Command(args[0])
 
 	if len(args) > 1 {
@@ -43,29 +43,44 @@ func (this *ActionDefinition) commandWithoutShell() *exec.Cmd {
 	return command
 }
 
-func (this *ActionDefinition) execute(workingDir string) (bool, error) {
-	if len(this.Command) == 0 {
-		return false, fmt.Errorf("Command of action `%s` does not have a command attached to it!\n", this.Name)
-	}
-
+func (this *ActionDefinition) shellCommand(commandString string) *exec.Cmd {
 	shellString := this.Shell
 
 	if shellString == "none" {
 		shellString = ""
 	}
 
-	var command *exec.Cmd
-
 	if len(shellString) > 0 {
-		command = this.commandWithShell(shellString)
-	} else {
-		command = this.commandWithoutShell()
+		return this.commandWithShell(shellString, commandString)
+	}
+
+	return this.commandWithoutShell(commandString)
+}
+
+func (this *ActionDefinition) execute(workingDir string, compilerDefinition *CompilerDefinition) (bool, error) {
+	if len(this.Command) == 0 {
+		return false, fmt.Errorf("Command of action `%s` does not have a command attached to it!\n", this.Name)
+	}
+
+	commandString, err := compilerDefinition.expandFindReferences(this.Command)
+
+	if err != nil {
+		return false, err
 	}
 
+	command := this.shellCommand(commandString)
 	command.Dir = workingDir
 
+	variables := compilerDefinition.variables()
+
 	for _, environmentVariable := range this.Environment {
-		command.Env = append(command.Env, os.ExpandEnv(environmentVariable))
+		command.Env = append(command.Env, os.Expand(environmentVariable, func(key string) string {
+			if value, ok := variables[key]; ok {
+				return value
+			}
+
+			return os.Getenv(key)
+		}))
 	}
 
 	captureStdout := slices.Contains(this.Output.Capture, "stdout")
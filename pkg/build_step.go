@@ -0,0 +1,64 @@
+package gmakec
+
+import (
+	"os"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// A single command of a target, e.g. compiling one source or linking the output.
+type BuildStep struct {
+	Command []string
+	Inputs  []string
+	Outputs []string
+}
+
+// A step needs to run when an output is missing or older than any input.
+func (this *BuildStep) needsRebuild() (bool, error) {
+	outputModTimes := []int64{}
+
+	for _, output := range this.Outputs {
+		modTimes, err := collectModTimes(output)
+
+		if err != nil {
+			return false, err
+		}
+
+		if len(modTimes) == 0 {
+			return true, nil
+		}
+
+		outputModTimes = append(outputModTimes, modTimes...)
+	}
+
+	inputModTimes, err := collectModTimesMultiple(this.Inputs)
+
+	if err != nil {
+		return false, err
+	}
+
+	return len(inputModTimes) > 0 && slices.Max(inputModTimes) > slices.Min(outputModTimes), nil
+}
+
+// Reads the dependencies of a make style depfile (-MD), a missing depfile has none.
+func depfileDependencies(path string) []string {
+	content, err := os.ReadFile(path)
+
+	if err != nil {
+		return []string{}
+	}
+
+	rules := strings.ReplaceAll(string(content), "\\\n", " ")
+	dependencies := []string{}
+
+	for _, line := range strings.Split(rules, "\n") {
+		_, prerequisites, found := strings.Cut(line, ": ")
+
+		if found {
+			dependencies = append(dependencies, strings.Fields(prerequisites)...)
+		}
+	}
+
+	return dependencies
+}
@@ -0,0 +1,351 @@
+package gmakec
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"golang.org/x/exp/slices"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	CHECK_TYPE_INCLUDE   string = "include"
+	CHECK_TYPE_SYMBOL    string = "symbol"
+	CHECK_TYPE_FUNCTION  string = "function"
+	CHECK_TYPE_TYPE_SIZE string = "type_size"
+	CHECK_TYPE_SOURCE    string = "source"
+)
+
+const CHECK_RESULTS_FILE string = "checks.yaml"
+
+// type sizes are searched up to this many bytes
+const MAX_CHECK_TYPE_SIZE int = 1024
+
+var checkVariablePattern = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// A configure time check compiling a tiny probe program with the compiler of the target.
+type CheckDefinition struct {
+	Type      string       `yaml:"type"`
+	Name      string       `yaml:"name"`
+	Headers   []string     `yaml:"headers"`
+	Source    string       `yaml:"source"`
+	Language  string       `yaml:"language"`
+	Flags     []string     `yaml:"flags"`
+	Libraries []string     `yaml:"libraries"`
+	Define    string       `yaml:"define"`
+	Result    *CheckResult `yaml:"-"`
+}
+
+type CheckResult struct {
+	Success bool   `yaml:"success"`
+	Value   string `yaml:"value"`
+}
+
+var checkResults = map[string]*CheckResult{}
+
+func loadCheckResults(configureDir string) {
+	content, err := os.ReadFile(filepath.Join(configureDir, CHECK_RESULTS_FILE))
+
+	if err != nil {
+		return
+	}
+
+	results := map[string]*CheckResult{}
+
+	if err := yaml.Unmarshal(content, &results); err != nil {
+		return
+	}
+
+	for key, result := range results {
+		if _, ok := checkResults[key]; !ok {
+			checkResults[key] = result
+		}
+	}
+}
+
+func saveCheckResults(configureDir string) error {
+	content, err := yaml.Marshal(checkResults)
+
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(configureDir, CHECK_RESULTS_FILE), content, 0644)
+}
+
+func (this *CheckDefinition) sanitize() error {
+	switch this.Type {
+	case CHECK_TYPE_INCLUDE, CHECK_TYPE_SYMBOL, CHECK_TYPE_FUNCTION, CHECK_TYPE_TYPE_SIZE:
+		if len(this.Name) == 0 {
+			return fmt.Errorf("Check of type `%s` needs to have the field `name` set!", this.Type)
+		}
+	case CHECK_TYPE_SOURCE:
+		if len(this.Source) == 0 || len(this.Define) == 0 {
+			return fmt.Errorf("Check of type `%s` needs to have the fields `source` and `define` set!", this.Type)
+		}
+	default:
+		return fmt.Errorf("Check `%s` has unsupported type `%s`!", this.Name, this.Type)
+	}
+
+	if len(this.Language) > 0 && this.Language != LANGUAGE_C && this.Language != LANGUAGE_CXX {
+		return fmt.Errorf("Check `%s` has unsupported language `%s`!", this.Name, this.Language)
+	}
+
+	return nil
+}
+
+// Variable and define name like HAVE_UNISTD_H or SIZEOF_VOID_P.
+func (this *CheckDefinition) variableName() string {
+	if len(this.Define) > 0 {
+		return this.Define
+	}
+
+	name := strings.ReplaceAll(this.Name, "*", " p ")
+	name = strings.Trim(checkVariablePattern.ReplaceAllString(name, "_"), "_")
+
+	if this.Type == CHECK_TYPE_TYPE_SIZE {
+		return fmt.Sprintf("SIZEOF_%s", strings.ToUpper(name))
+	}
+
+	return fmt.Sprintf("HAVE_%s", strings.ToUpper(name))
+}
+
+func (this *CheckDefinition) description() string {
+	switch this.Type {
+	case CHECK_TYPE_INCLUDE:
+		return fmt.Sprintf("Looking for include file %s", this.Name)
+	case CHECK_TYPE_TYPE_SIZE:
+		return fmt.Sprintf("Checking size of %s", this.Name)
+	case CHECK_TYPE_SOURCE:
+		return fmt.Sprintf("Performing test %s", this.Define)
+	}
+
+	return fmt.Sprintf("Looking for %s %s", this.Type, this.Name)
+}
+
+func (this *CheckDefinition) includes() string {
+	includes := strings.Builder{}
+
+	for _, header := range this.Headers {
+		includes.WriteString(fmt.Sprintf("#include <%s>\n", header))
+	}
+
+	return includes.String()
+}
+
+// The probe program and whether it needs to be linked.
+func (this *CheckDefinition) program(size int) (string, bool) {
+	switch this.Type {
+	case CHECK_TYPE_INCLUDE:
+		return fmt.Sprintf("#include <%s>\n\nint main(void) { return 0; }\n", this.Name), false
+	case CHECK_TYPE_SYMBOL:
+		// symbols can be macros as well
+		return fmt.Sprintf(
+			"%s\nint main(void) {\n#ifndef %s\n  return ((int *)(&%s))[0];\n#else\n  return 0;\n#endif\n}\n",
+			this.includes(), this.Name, this.Name,
+		), true
+	case CHECK_TYPE_FUNCTION:
+		// declared without headers, only the linker needs to find it
+		return fmt.Sprintf(
+			"#ifdef __cplusplus\nextern \"C\"\n#endif\nchar %s(void);\n\nint main(void) { return %s(); }\n",
+			this.Name, this.Name,
+		), true
+	case CHECK_TYPE_TYPE_SIZE:
+		// fails to compile unless the size is at most the given one, so it works when cross compiling
+		return fmt.Sprintf(
+			"%s\ntypedef char check_size[(sizeof(%s) <= %d) ? 1 : -1];\n\nint main(void) { return 0; }\n",
+			this.includes(), this.Name, size,
+		), false
+	}
+
+	return this.Source, true
+}
+
+type checkCompiler struct {
+	compilerDef *CompilerDefinition
+	flags       []string
+	libraries   []string
+	workingDir  string
+	checkDir    string
+}
+
+func (this *checkCompiler) compiles(check *CheckDefinition, language string, size int) bool {
+	program, link := check.program(size)
+	source := filepath.Join(this.checkDir, fmt.Sprintf("check%s", languageExtensions[language][0]))
+	output := filepath.Join(this.checkDir, "check")
+
+	if err := os.WriteFile(source, []byte(program), 0644); err != nil {
+		return false
+	}
+
+	compiler := this.compilerDef.Object
+	command := compiler.command()
+	command = append(command, this.compilerDef.Flags...)
+	command = append(command, this.flags...)
+	command = append(command, check.Flags...)
+
+	if link {
+		command = append(command, compiler.outputFlags(output)...)
+		command = append(command, source)
+		command = append(command, compiler.linkArguments(append(slices.Clone(this.libraries), check.Libraries...))...)
+	} else {
+		command = append(command, compiler.CompileOnlyFlag, source)
+		command = append(command, compiler.objectOutputFlags(output)...)
+	}
+
+	process := exec.Command(command[0], command[1:]...)
+	process.Dir = this.workingDir
+	return process.Run() == nil
+}
+
+func (this *checkCompiler) run(check *CheckDefinition, language string) *CheckResult {
+	if check.Type != CHECK_TYPE_TYPE_SIZE {
+		return &CheckResult{Success: this.compiles(check, language, 0), Value: "1"}
+	}
+
+	if !this.compiles(check, language, MAX_CHECK_TYPE_SIZE) {
+		return &CheckResult{}
+	}
+
+	low, high := 0, MAX_CHECK_TYPE_SIZE
+
+	for low+1 < high {
+		middle := (low + high) / 2
+
+		if this.compiles(check, language, middle) {
+			high = middle
+		} else {
+			low = middle
+		}
+	}
+
+	return &CheckResult{Success: true, Value: fmt.Sprintf("%d", high)}
+}
+
+func (this *CheckDefinition) cacheKey(compilerDef *CompilerDefinition, flags []string, language string) string {
+	definition, _ := yaml.Marshal(this)
+	key := strings.Join(append(append([]string{compilerDef.Object.Path, language}, compilerDef.Flags...), flags...), " ")
+	return fmt.Sprintf("%x", sha1.Sum(append([]byte(key), definition...)))
+}
+
+// Runs the checks of a target, results are cached in the configure directory.
+func (this *TargetDefinition) runChecks(
+	definitionContext *DefinitionContext, languages map[string]*CompilerDefinition,
+) error {
+	checksDir := filepath.Join(definitionContext.ConfigureDir, "checks")
+	defer RemovePath(checksDir)
+
+	for index := range this.Checks {
+		check := &this.Checks[index]
+
+		if err := check.sanitize(); err != nil {
+			return err
+		}
+
+		language := check.Language
+		compilerDef := &this.Compiler
+
+		if compilerDef.Object == nil {
+			return fmt.Errorf("Check `%s` of target `%s` needs a compiler!", check.Name, this.Name)
+		}
+
+		if len(language) == 0 {
+			language = this.language()
+		}
+
+		if len(languages) > 0 {
+			if compilerDef = languages[language]; compilerDef == nil {
+				return fmt.Errorf("Check `%s` of target `%s` needs a %s compiler!", check.Name, this.Name, language)
+			}
+		}
+
+		flags, libraries := compilerDef.findFlags()
+		key := check.cacheKey(compilerDef, flags, language)
+		result, cached := checkResults[key]
+
+		if !cached {
+			checkDir := filepath.Join(checksDir, key)
+
+			if err := os.MkdirAll(checkDir, os.ModePerm); err != nil {
+				return err
+			}
+
+			absoluteCheckDir, err := filepath.Abs(checkDir)
+
+			if err != nil {
+				return err
+			}
+
+			checker := checkCompiler{
+				compilerDef: compilerDef,
+				flags:       flags,
+				libraries:   libraries,
+				workingDir:  definitionContext.DefinitionPath,
+				checkDir:    absoluteCheckDir,
+			}
+
+			result = checker.run(check, language)
+			checkResults[key] = result
+		}
+
+		check.Result = result
+		status := "not found"
+
+		if result.Success {
+			status = "found"
+
+			if check.Type == CHECK_TYPE_TYPE_SIZE {
+				status = result.Value
+			} else if check.Type == CHECK_TYPE_SOURCE {
+				status = "success"
+			}
+		} else if check.Type == CHECK_TYPE_SOURCE {
+			status = "failed"
+		}
+
+		fmt.Printf("[check] %s - %s\n", check.description(), status)
+	}
+
+	return nil
+}
+
+// Variables like HAVE_UNISTD_H (1 or 0) or SIZEOF_INT for configure files.
+func (this *TargetDefinition) checkVariables() map[string]string {
+	variables := map[string]string{}
+
+	for _, check := range this.Checks {
+		if check.Result == nil {
+			continue
+		}
+
+		switch {
+		case check.Result.Success:
+			variables[check.variableName()] = check.Result.Value
+		case check.Type == CHECK_TYPE_TYPE_SIZE:
+			variables[check.variableName()] = ""
+		default:
+			variables[check.variableName()] = "0"
+		}
+	}
+
+	return variables
+}
+
+// Defines of the successful checks, e.g. -DHAVE_UNISTD_H=1.
+func (this *TargetDefinition) checkDefines() []string {
+	defines := []string{}
+
+	for _, check := range this.Checks {
+		if check.Result != nil && check.Result.Success {
+			defines = append(defines, this.Compiler.Object.DefineFlag)
+			defines = append(defines, fmt.Sprintf("%s=%s", check.variableName(), check.Result.Value))
+		}
+	}
+
+	return defines
+}
@@ -1,10 +1,17 @@
 package gmakec
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
 	"os/exec"
+	"time"
 )
 
+// Probed programs might wait for input or hang, e.g. unknown candidates of a find.
+const PROBE_TIMEOUT time.Duration = 10 * time.Second
+
 func executeCommand(command *exec.Cmd, workingDir string) error {
 	command.Dir = workingDir
 	command.Stdout = os.Stdout
@@ -12,3 +19,20 @@ func executeCommand(command *exec.Cmd, workingDir string) error {
 
 	return command.Run()
 }
+
+// Runs a program to probe it and returns its combined stdout and stderr.
+func probeCommandOutput(path string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), PROBE_TIMEOUT)
+	defer cancel()
+
+	command := exec.CommandContext(ctx, path, args...)
+	// children keeping the output open must not block either
+	command.WaitDelay = time.Second
+	output, err := command.CombinedOutput()
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return output, fmt.Errorf("timed out after %s", PROBE_TIMEOUT)
+	}
+
+	return output, err
+}
@@ -2,47 +2,300 @@ package gmakec
 
 import (
 	"fmt"
+	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
 )
 
+// A compiler family describes the flag spellings of compatible compiler drivers.
+// Resolved compilers are copies of their family with name and path set.
 type Compiler struct {
-	Name              string
-	Path              string
-	DefineFlag        string
-	IncludeSearchFlag string
-	LinkSearchFlag    string
-	OutputFlag        string
+	Name      string   `yaml:"name"`
+	Extends   string   `yaml:"extends"`
+	Names     []string `yaml:"names"`
+	Arguments []string `yaml:"arguments"`
+	Macro     string   `yaml:"macro"`
+	Family    string   `yaml:"-"`
+	Path      string   `yaml:"-"`
+
+	Probe *CompilerProbe `yaml:"-"`
+
+	DefineFlag        string   `yaml:"define_flag"`
+	IncludeSearchFlag string   `yaml:"include_flag"`
+	LinkSearchFlag    string   `yaml:"link_search_flag"`
+	OutputFlag        string   `yaml:"output_flag"`
+	ObjectOutputFlag  string   `yaml:"object_output_flag"`
+	JoinOutputFlags   bool     `yaml:"join_output_flags"`
+	CompileOnlyFlag   string   `yaml:"compile_only_flag"`
+	LinkerFlag        string   `yaml:"linker_flag"`
+	LibraryFlag       string   `yaml:"library_flag"`
+	LibraryExtension  string   `yaml:"library_extension"`
+	SharedFlag        string   `yaml:"shared_flag"`
+	DepfileFlags      []string `yaml:"depfile_flags"`
+	LanguageFlag      string   `yaml:"language_flag"`
+	RPathFlag         string   `yaml:"rpath_flag"`
+	SysrootFlag       string   `yaml:"sysroot_flag"`
+	TargetFlag        string   `yaml:"target_flag"`
+	ForceIncludeFlag  string   `yaml:"force_include_flag"`
+	ObjectExtension   string   `yaml:"object_extension"`
+
+	WarningsAsErrorsFlag string              `yaml:"warnings_as_errors_flag"`
+	WarningFlags         map[string][]string `yaml:"warning_flags"`
+	OptimizationFlags    map[string][]string `yaml:"optimization_flags"`
+	DebugInfoFlag        string              `yaml:"debug_info_flag"`
+
+	StandardFlag                string `yaml:"standard_flag"`
+	CStandardPrefix             string `yaml:"c_standard_prefix"`
+	CxxStandardPrefix           string `yaml:"cxx_standard_prefix"`
+	ExtensionsCStandardPrefix   string `yaml:"extensions_c_standard_prefix"`
+	ExtensionsCxxStandardPrefix string `yaml:"extensions_cxx_standard_prefix"`
+	NoExtensionsFlag            string `yaml:"no_extensions_flag"`
+
+	PrecompiledHeaderExtension   string `yaml:"pch_extension"`
+	IncludePrecompiledHeaderFlag string `yaml:"include_pch_flag"`
+
+	// the keys set in YAML, only the other fields are inherited
+	keys []string
+}
+
+func (this *Compiler) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plainCompiler Compiler
+	fields := map[string]interface{}{}
+
+	if err := unmarshal(&fields); err != nil {
+		return err
+	}
+
+	if err := unmarshal((*plainCompiler)(this)); err != nil {
+		return err
+	}
+
+	this.keys = maps.Keys(fields)
+	return nil
 }
 
+// The built-in families, each definition context extends them by its own ones.
 var compilers []*Compiler
 
-func fromCompilerTemplate(compilerTemplate *Compiler, name string) *Compiler {
+func fromCompilerTemplate(compilerTemplate *Compiler, name string, names ...string) *Compiler {
 	cc := *compilerTemplate
 	cc.Name = name
+	cc.Names = names
 
 	return &cc
 }
 
 func InitCompilers() {
 	compilerTemplate := &Compiler{
-		DefineFlag:        "-D",
-		IncludeSearchFlag: "-I",
-		LinkSearchFlag:    "-L",
-		OutputFlag:        "-o",
+		DefineFlag:                 "-D",
+		IncludeSearchFlag:          "-I",
+		LinkSearchFlag:             "-L",
+		OutputFlag:                 "-o",
+		CompileOnlyFlag:            "-c",
+		LibraryFlag:                "-l",
+		SharedFlag:                 "-shared",
+		DepfileFlags:               []string{"-MD", "-MF"},
+		LanguageFlag:               "-x",
+		RPathFlag:                  "-Wl,-rpath,",
+		SysrootFlag:                "--sysroot=",
+		ForceIncludeFlag:           "-include",
+		ObjectExtension:            ".o",
+		PrecompiledHeaderExtension: ".gch",
+		WarningsAsErrorsFlag:       "-Werror",
+		WarningFlags: map[string][]string{
+			WARNINGS_ALL:      {"-Wall"},
+			WARNINGS_EXTRA:    {"-Wextra"},
+			WARNINGS_PEDANTIC: {"-pedantic"},
+			WARNINGS_ERROR:    {"-Werror"},
+		},
+		OptimizationFlags: map[string][]string{
+			"0": {"-O0"},
+			"1": {"-O1"},
+			"2": {"-O2"},
+			"3": {"-O3"},
+			"s": {"-Os"},
+		},
+		DebugInfoFlag:               "-g",
+		StandardFlag:                "-std=",
+		CStandardPrefix:             "c",
+		CxxStandardPrefix:           "c++",
+		ExtensionsCStandardPrefix:   "gnu",
+		ExtensionsCxxStandardPrefix: "gnu++",
 	}
 
+	gccTemplate := *compilerTemplate
+	gccTemplate.Macro = "__GNUC__"
+
+	clangTemplate := *compilerTemplate
+	clangTemplate.Macro = "__clang__"
+	clangTemplate.TargetFlag = "--target="
+	clangTemplate.PrecompiledHeaderExtension = ".pch"
+	clangTemplate.IncludePrecompiledHeaderFlag = "-include-pch"
+
+	// cl.exe style drivers, options after /link are passed to the linker
+	msvcTemplate := &Compiler{
+		Macro:             "_MSC_VER",
+		DefineFlag:        "/D",
+		IncludeSearchFlag: "/I",
+		LinkSearchFlag:    "/LIBPATH:",
+		OutputFlag:        "/Fe",
+		ObjectOutputFlag:  "/Fo",
+		JoinOutputFlags:   true,
+		CompileOnlyFlag:   "/c",
+		SharedFlag:        "/LD",
+		LinkerFlag:        "/link",
+		LibraryExtension:  ".lib",
+		ForceIncludeFlag:  "/FI",
+		ObjectExtension:   ".obj",
+
+		WarningsAsErrorsFlag: "/WX",
+		WarningFlags: map[string][]string{
+			WARNINGS_ALL:      {"/W3"},
+			WARNINGS_EXTRA:    {"/W4"},
+			WARNINGS_PEDANTIC: {"/permissive-"},
+			WARNINGS_ERROR:    {"/WX"},
+		},
+		// /O1 optimizes for size, /O2 for speed
+		OptimizationFlags: map[string][]string{
+			"0": {"/Od"},
+			"1": {"/O2"},
+			"2": {"/O2"},
+			"3": {"/O2"},
+			"s": {"/O1"},
+		},
+		DebugInfoFlag: "/Zi",
+		// MSVC has no GNU dialects, its extensions are disabled by strict conformance mode
+		StandardFlag:      "/std:",
+		CStandardPrefix:   "c",
+		CxxStandardPrefix: "c++",
+		NoExtensionsFlag:  "/permissive-",
+	}
+
+	clangClTemplate := *msvcTemplate
+	clangClTemplate.TargetFlag = "--target="
+
+	// clang defines __GNUC__ as well, so it needs to be probed first
 	compilers = make([]*Compiler, 0)
 	compilers = []*Compiler{
-		fromCompilerTemplate(compilerTemplate, "gcc"),
-		fromCompilerTemplate(compilerTemplate, "g++"),
-		fromCompilerTemplate(compilerTemplate, "clang"),
-		fromCompilerTemplate(compilerTemplate, "clang++"),
+		fromCompilerTemplate(msvcTemplate, "msvc", "cl", "cl.exe"),
+		fromCompilerTemplate(&clangClTemplate, "clang-cl", append(driverNamePatterns("clang-cl"), "clang-cl.exe")...),
+		fromCompilerTemplate(&clangTemplate, "clang", driverNamePatterns("clang", "clang++")...),
+		fromCompilerTemplate(&gccTemplate, "gcc", driverNamePatterns("gcc", "g++")...),
 	}
 }
 
-func findCompilerByPath(lookedPath string) (*Compiler, error) {
-	lookedPath, err := exec.LookPath(lookedPath)
+// Matches versioned (gcc-13) and target prefixed (arm-none-eabi-gcc) driver names.
+func driverNamePatterns(names ...string) []string {
+	patterns := []string{}
+
+	for _, name := range names {
+		patterns = append(patterns, name, fmt.Sprintf("%s-[0-9]*", name))
+		patterns = append(patterns, fmt.Sprintf("*-%s", name), fmt.Sprintf("*-%s-[0-9]*", name))
+	}
+
+	return patterns
+}
+
+func findCompilerFamily(families []*Compiler, name string) *Compiler {
+	for index := range families {
+		if families[index].Name == name {
+			return families[index]
+		}
+	}
+
+	return nil
+}
+
+// Fills all fields not set by the family with the ones of the family it extends.
+// Maps like warning_flags are merged, so single entries can be overridden.
+func (this *Compiler) inherit(family *Compiler) {
+	value := reflect.ValueOf(this).Elem()
+	familyValue := reflect.ValueOf(family).Elem()
+
+	for index := 0; index < value.NumField(); index++ {
+		field := value.Type().Field(index)
+
+		if field.Name == "Names" || !field.IsExported() {
+			continue
+		}
+
+		if !slices.Contains(this.keys, strings.Split(field.Tag.Get("yaml"), ",")[0]) {
+			value.Field(index).Set(familyValue.Field(index))
+			continue
+		}
+
+		if field.Type.Kind() == reflect.Map && !familyValue.Field(index).IsNil() {
+			merged := reflect.MakeMap(field.Type)
+
+			for _, fieldValue := range []reflect.Value{familyValue.Field(index), value.Field(index)} {
+				iterator := fieldValue.MapRange()
+
+				for iterator.Next() {
+					merged.SetMapIndex(iterator.Key(), iterator.Value())
+				}
+			}
+
+			value.Field(index).Set(merged)
+		}
+	}
+}
+
+// Families defined in YAML replace the built-in one of the same name, others are appended,
+// so probing an unknown compiler prefers the built-in families.
+func registerCompilerFamily(families []*Compiler, family Compiler) ([]*Compiler, error) {
+	if len(family.Name) == 0 {
+		return nil, fmt.Errorf("Compiler family with names %v needs to have the field `name` set!", family.Names)
+	}
+
+	if len(family.Extends) > 0 {
+		base := findCompilerFamily(families, family.Extends)
+
+		if base == nil {
+			return nil, fmt.Errorf("Compiler family `%s` extends unknown compiler family `%s`!", family.Name, family.Extends)
+		}
+
+		family.inherit(base)
+	}
+
+	if len(family.Names) == 0 {
+		return nil, fmt.Errorf("Compiler family `%s` needs to have the field `names` set!", family.Name)
+	}
+
+	if len(family.OutputFlag) == 0 || len(family.CompileOnlyFlag) == 0 {
+		return nil, fmt.Errorf("Compiler family `%s` needs to have the fields `output_flag` and `compile_only_flag` set!", family.Name)
+	}
+
+	families = slices.Clone(families)
+	index := slices.IndexFunc(families, func(compiler *Compiler) bool {
+		return compiler.Name == family.Name
+	})
+
+	if index < 0 {
+		return append(families, &family), nil
+	}
+
+	families[index] = &family
+	return families, nil
+}
+
+func (this *Compiler) matches(name string) bool {
+	for _, pattern := range this.Names {
+		if matched, err := filepath.Match(pattern, name); err == nil && matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+func findCompilerByPath(families []*Compiler, lookedPath string) (*Compiler, error) {
+	lookedPath, err := exec.LookPath(toolchainProgram(lookedPath))
 
 	if err != nil {
 		return nil, fmt.Errorf("Compiler of path `%s` not found!", lookedPath)
@@ -54,14 +307,195 @@ func findCompilerByPath(lookedPath string) (*Compiler, error) {
 		return nil, fmt.Errorf("Absolute path for compiler with path `%s` not found!", lookedPath)
 	}
 
-	name := filepath.Base(absolutePath)
+	family := findCompilerFamilyByPath(families, absolutePath)
+
+	if family == nil {
+		return nil, fmt.Errorf("Compiler of path `%s` is not supported (yet)!", absolutePath)
+	}
+
+	compiler := *family
+	compiler.Family = compiler.Name
+	compiler.Name = filepath.Base(absolutePath)
+	compiler.Path = absolutePath
+	compiler.Probe = probeCompiler(&compiler)
+	return &compiler, nil
+}
+
+// Identifies the family by the executable name, the names of the symlinks it
+// points to (cc -> /etc/alternatives/cc -> gcc) and finally by probing it.
+// Name patterns of families defined in YAML take precedence, e.g. over `*-gcc` of gcc.
+func findCompilerFamilyByPath(families []*Compiler, path string) *Compiler {
+	for _, name := range symlinkNames(path) {
+		for _, builtin := range []bool{false, true} {
+			for index := range families {
+				if slices.Contains(compilers, families[index]) == builtin && families[index].matches(name) {
+					return families[index]
+				}
+			}
+		}
+	}
+
+	return probeCompilerFamily(families, path)
+}
+
+func symlinkNames(path string) []string {
+	names := []string{filepath.Base(path)}
+
+	// limited in case of symlink loops
+	for depth := 0; depth < 32; depth++ {
+		target, err := os.Readlink(path)
+
+		if err != nil {
+			break
+		}
+
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(path), target)
+		}
+
+		path = target
+		names = append(names, filepath.Base(path))
+	}
+
+	return names
+}
 
-	for index := range compilers {
-		if compilers[index].Name == name {
-			compilers[index].Path = absolutePath
-			return compilers[index], nil
+// Checks the predefined macros of the compiler, falls back to its version output.
+func probeCompilerFamily(families []*Compiler, path string) *Compiler {
+	macros, err := probeCommandOutput(path, "-dM", "-E", "-x", "c", os.DevNull)
+
+	if err == nil {
+		for index := range families {
+			if len(families[index].Macro) == 0 {
+				continue
+			}
+
+			pattern := regexp.MustCompile(fmt.Sprintf(`(?m)^#define %s\b`, regexp.QuoteMeta(families[index].Macro)))
+
+			if pattern.Match(macros) {
+				return families[index]
+			}
 		}
 	}
 
-	return nil, fmt.Errorf("Compiler of path `%s` is not supported (yet)!", absolutePath)
+	version, err := probeCommandOutput(path, "--version")
+
+	if err != nil {
+		return nil
+	}
+
+	for index := range families {
+		if strings.Contains(strings.ToLower(string(version)), strings.ToLower(families[index].Name)) {
+			return families[index]
+		}
+	}
+
+	return nil
+}
+
+// C++ for drivers like g++, clang++-17 or x86_64-linux-gnu-g++-12, C otherwise.
+func (this *Compiler) defaultLanguage() string {
+	if strings.Contains(this.Name, "++") {
+		return LANGUAGE_CXX
+	}
+
+	return LANGUAGE_C
+}
+
+// The executable and its arguments, e.g. `zig cc`.
+func (this *Compiler) command() []string {
+	return append([]string{this.Path}, this.Arguments...)
+}
+
+func (this *Compiler) outputFlags(output string) []string {
+	if this.JoinOutputFlags {
+		return []string{fmt.Sprintf("%s%s", this.OutputFlag, output)}
+	}
+
+	return []string{this.OutputFlag, output}
+}
+
+func (this *Compiler) objectOutputFlags(object string) []string {
+	flag := this.ObjectOutputFlag
+
+	if len(flag) == 0 {
+		flag = this.OutputFlag
+	}
+
+	if this.JoinOutputFlags {
+		return []string{fmt.Sprintf("%s%s", flag, object)}
+	}
+
+	return []string{flag, object}
+}
+
+func (this *Compiler) rpathFlags(rpaths []string) []string {
+	flags := []string{}
+
+	// e.g. MSVC has no RPATH
+	if len(this.RPathFlag) == 0 {
+		return flags
+	}
+
+	for _, rpath := range rpaths {
+		flags = append(flags, fmt.Sprintf("%s%s", this.RPathFlag, rpath))
+	}
+
+	return flags
+}
+
+// Translates the POSIX style -L and -l flags of links and find results, e.g. -lz to z.lib.
+// Drivers with a linker flag need the search paths passed after it.
+func (this *Compiler) linkArguments(arguments []string) []string {
+	libraries := []string{}
+	linkerArguments := []string{}
+
+	for index := 0; index < len(arguments); index++ {
+		argument := arguments[index]
+
+		// search flags separated from their directory, e.g. `-L build`
+		if (argument == this.LinkSearchFlag || argument == "-L") && index+1 < len(arguments) {
+			index++
+			argument = fmt.Sprintf("%s%s", argument, arguments[index])
+		}
+
+		switch {
+		case strings.HasPrefix(argument, this.LinkSearchFlag):
+			linkerArguments = append(linkerArguments, argument)
+		case strings.HasPrefix(argument, "-L"):
+			linkerArguments = append(linkerArguments, fmt.Sprintf("%s%s", this.LinkSearchFlag, argument[2:]))
+		case strings.HasPrefix(argument, "-l"):
+			libraries = append(libraries, fmt.Sprintf("%s%s%s", this.LibraryFlag, argument[2:], this.LibraryExtension))
+		default:
+			libraries = append(libraries, argument)
+		}
+
+		if len(this.LinkerFlag) == 0 {
+			libraries = append(libraries, linkerArguments...)
+			linkerArguments = []string{}
+		}
+	}
+
+	if len(linkerArguments) == 0 {
+		return libraries
+	}
+
+	return append(append(libraries, this.LinkerFlag), linkerArguments...)
+}
+
+func (this *Compiler) depfileFlags(depfile string) []string {
+	if len(this.DepfileFlags) == 0 {
+		return []string{}
+	}
+
+	return append(append([]string{}, this.DepfileFlags...), depfile)
+}
+
+func (this *Compiler) precompiledHeaderFlags(precompiledHeader string) []string {
+	if len(this.IncludePrecompiledHeaderFlag) > 0 {
+		return []string{this.IncludePrecompiledHeaderFlag, precompiledHeader}
+	}
+
+	// gcc picks up <header>.gch when force including the header next to it
+	return []string{this.ForceIncludeFlag, strings.TrimSuffix(precompiledHeader, this.PrecompiledHeaderExtension)}
 }
@@ -3,12 +3,15 @@ package gmakec
 import (
 	"fmt"
 	"log"
-	"os"
 	"os/exec"
-	"path/filepath"
+	"regexp"
 	"strings"
+
+	"golang.org/x/exp/slices"
 )
 
+var findReferencePattern = regexp.MustCompile(`<find:([^:>]+):([^:>]+)>`)
+
 type CompilerDefinition struct {
 	Name   string                   `yaml:"name"`
 	Ref    string                   `yaml:"ref"`
@@ -16,6 +19,25 @@ type CompilerDefinition struct {
 	Flags  []string                 `yaml:"flags"`
 	Find   []CompilerFindDefinition `yaml:"find"`
 	Object *Compiler
+
+	// only passed if the compiler supports them
+	CheckFlags []string `yaml:"check_flags"`
+
+	Launcher     string `yaml:"launcher"`
+	LinkLauncher string `yaml:"link_launcher"`
+
+	LanguageSettings `yaml:",inline"`
+}
+
+// Overrides the launchers of all compilers when set from the command line.
+var launcher, linkLauncher *string
+
+func SetLauncher(command string) {
+	launcher = &command
+}
+
+func SetLinkLauncher(command string) {
+	linkLauncher = &command
 }
 
 func (this *CompilerDefinition) findRef(refCompilerDefinitions *[]CompilerDefinition) *CompilerDefinition {
@@ -28,19 +50,21 @@ func (this *CompilerDefinition) findRef(refCompilerDefinitions *[]CompilerDefini
 	return nil
 }
 
-func (this *CompilerDefinition) withRef(refCompilerDefinitions *[]CompilerDefinition) (*CompilerDefinition, error) {
+func (this *CompilerDefinition) withRef(definitionContext *DefinitionContext) (*CompilerDefinition, error) {
+	families := definitionContext.Definition.families
+
 	if len(this.Ref) == 0 {
 		if len(this.Path) == 0 {
 			return nil, fmt.Errorf("Non-ref compiler definition of name `%s` need to have the field `path` set!", this.Name)
 		}
 
-		path, err := exec.LookPath(this.Path)
+		path, err := exec.LookPath(toolchainProgram(this.Path))
 
 		if err != nil {
 			return nil, fmt.Errorf("Non-ref compiler path `%s` not found!", this.Path)
 		}
 
-		object, err := findCompilerByPath(path)
+		object, err := findCompilerByPath(families, path)
 
 		if err != nil {
 			return nil, err
@@ -51,86 +75,157 @@ func (this *CompilerDefinition) withRef(refCompilerDefinitions *[]CompilerDefini
 		return this, nil
 	}
 
-	compilerRef := this.findRef(refCompilerDefinitions)
+	compilerRef := this.findRef(&definitionContext.Definition.Compilers)
 
 	if compilerRef == nil {
 		return nil, fmt.Errorf("Could not find compiler ref: %s\n", this.Ref)
 	}
 
-	object, err := findCompilerByPath(compilerRef.Path)
+	object, err := findCompilerByPath(families, compilerRef.Path)
 
 	if err != nil {
 		return nil, err
 	}
 
+	// clone to not share the backing arrays (and find results) with other targets referencing the same compiler
 	compilerRef.Object = object
-	compilerRef.Flags = append(compilerRef.Flags, this.Flags...)
-	compilerRef.Find = append(compilerRef.Find, this.Find...)
+	compilerRef.Flags = append(slices.Clone(compilerRef.Flags), this.Flags...)
+	compilerRef.Find = append(slices.Clone(compilerRef.Find), this.Find...)
+	compilerRef.CheckFlags = append(slices.Clone(compilerRef.CheckFlags), this.CheckFlags...)
+
+	if len(this.Launcher) > 0 {
+		compilerRef.Launcher = this.Launcher
+	}
+
+	if len(this.LinkLauncher) > 0 {
+		compilerRef.LinkLauncher = this.LinkLauncher
+	}
+
+	compilerRef.LanguageSettings = compilerRef.LanguageSettings.merged(this.LanguageSettings)
+
 	return compilerRef, nil
 }
 
-func (this *CompilerDefinition) sanitize(refCompilerDefinitions *[]CompilerDefinition) (*CompilerDefinition, error) {
-	var err error
-	this, err = this.withRef(refCompilerDefinitions)
+// Wrapper commands like ccache prefixed to compile steps.
+func (this *CompilerDefinition) launcher() []string {
+	if launcher != nil {
+		return strings.Fields(*launcher)
+	}
 
-	if err != nil {
-		return nil, err
+	return strings.Fields(this.Launcher)
+}
+
+// Wrapper commands prefixed to link steps, separate since e.g. ccache cannot cache links.
+func (this *CompilerDefinition) linkLauncher() []string {
+	if linkLauncher != nil {
+		return strings.Fields(*linkLauncher)
 	}
 
-	notFoundIndices := []int{}
+	return strings.Fields(this.LinkLauncher)
+}
+
+// Collects the compile and link flags of all find results, e.g. of pkg-config modules.
+func (this *CompilerDefinition) findFlags() ([]string, []string) {
+	flags := []string{}
+	links := []string{}
+
+	for _, find := range this.Find {
+		for _, result := range find.Results {
+			flags = append(flags, result.Flags...)
+			links = append(links, result.Links...)
+		}
+	}
+
+	return flags, links
+}
 
-	for index, find := range this.Find {
-		paths := []string{}
+// Variables like FIND_PYTHON3_PATH for all find results.
+func (this *CompilerDefinition) findVariables() map[string]string {
+	variables := map[string]string{}
 
-		if len(find.Paths) > 0 {
-			for _, findPath := range find.Paths {
-				// TODO: this is not portable at all, POSIX only. But it works for now.
-				paths = append(paths, os.ExpandEnv(strings.ReplaceAll(findPath, "~", "${HOME}")))
+	for _, find := range this.Find {
+		for _, result := range find.Results {
+			for field, value := range result.fields() {
+				variables[findVariableName(result.File, field)] = value
 			}
 		}
+	}
 
-		paths = append(paths, strings.Split(os.Getenv("PATH"), ":")...)
-		// TODO: add the ones defined in this project, if we will ever do that
+	return variables
+}
 
-		if find.Type == "filesystem" {
-			for _, file := range find.Names {
-				found := false
+// Find variables and the probed facts of the compiler, e.g. COMPILER_VERSION.
+func (this *CompilerDefinition) variables() map[string]string {
+	variables := this.findVariables()
 
-				for _, filePath := range paths {
-					fullPath := filepath.Join(filePath, file)
-					_, err := os.Stat(fullPath)
+	for key, value := range toolchainVariables() {
+		variables[key] = value
+	}
 
-					if err != nil {
-						if os.IsNotExist(err) {
-							continue
-						}
+	if this.Object == nil {
+		return variables
+	}
 
-						return nil, err
-					}
+	for key, value := range this.Object.variables() {
+		variables[key] = value
+	}
 
-					found = true
-					this.Find[index].Results = append(this.Find[index].Results, CompilerFindResult{
-						Type: find.Type,
-						File: file,
-						Path: fullPath,
-					})
+	return variables
+}
 
-					break
-				}
+// Replaces references like <find:python3:path> with the respective find result field.
+func (this *CompilerDefinition) expandFindReferences(text string) (string, error) {
+	var err error
 
-				if !found {
-					notFoundIndices = append(notFoundIndices, index)
-				}
-			}
+	expanded := findReferencePattern.ReplaceAllStringFunc(text, func(match string) string {
+		submatches := findReferencePattern.FindStringSubmatch(match)
+		value, ok := this.findVariables()[findVariableName(submatches[1], submatches[2])]
+
+		if !ok {
+			err = fmt.Errorf("Could not resolve `%s`, no such find result or field!", match)
+			return match
+		}
+
+		return value
+	})
+
+	return expanded, err
+}
+
+func (this *CompilerDefinition) sanitize(definitionContext *DefinitionContext) (*CompilerDefinition, error) {
+	var err error
+	this, err = this.withRef(definitionContext)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if toolchain != nil {
+		this.Flags = append(toolchain.compilerFlags(this.Object), this.Flags...)
+	}
+
+	notFoundIndices := []int{}
+
+	for index := range this.Find {
+		notFound, err := this.Find[index].find(this.Object)
+
+		if err != nil {
+			return nil, err
 		}
 
-		// TODO: implement search for libraries
+		if len(notFound) > 0 {
+			notFoundIndices = append(notFoundIndices, index)
+		}
 	}
 
 	if len(notFoundIndices) > 0 {
 		for _, index := range notFoundIndices {
 			find := this.Find[index]
 			log.Printf("ERROR: Could not find %s object of names %v and paths %v\n", find.Type, find.Names, find.Paths)
+
+			for _, rejection := range find.Rejected {
+				log.Printf("ERROR:   found but rejected `%s`: %s\n", rejection.Path, rejection.Reason)
+			}
 		}
 
 		return nil, fmt.Errorf("Exiting...")
@@ -1,14 +1,364 @@
 package gmakec
 
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/exp/slices"
+)
+
+const DEFAULT_VERSION_COMMAND string = "--version"
+const DEFAULT_VERSION_REGEX string = `(\d+(?:\.\d+)+)`
+
 type CompilerFindResult struct {
-	Type string
-	File string
-	Path string
+	Type    string
+	File    string
+	Path    string
+	Version string
+	Flags   []string
+	Links   []string
+}
+
+func findVariableName(name string, field string) string {
+	sanitizedName := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+
+		return '_'
+	}, name)
+
+	return fmt.Sprintf("FIND_%s_%s", sanitizedName, strings.ToUpper(field))
+}
+
+func (this *CompilerFindResult) fields() map[string]string {
+	return map[string]string{
+		"path":    this.Path,
+		"dir":     filepath.Dir(this.Path),
+		"file":    this.File,
+		"version": this.Version,
+		"flags":   strings.Join(this.Flags, " "),
+		"links":   strings.Join(this.Links, " "),
+	}
+}
+
+type CompilerFindRejection struct {
+	Path   string
+	Reason string
 }
 
 type CompilerFindDefinition struct {
-	Type    string   `yaml:"type"`
-	Names   []string `yaml:"names"`
-	Paths   []string `yaml:"paths"`
-	Results []CompilerFindResult
+	Type           string   `yaml:"type"`
+	Names          []string `yaml:"names"`
+	Paths          []string `yaml:"paths"`
+	Version        string   `yaml:"version"`
+	VersionCommand string   `yaml:"version_command"`
+	VersionRegex   string   `yaml:"version_regex"`
+	Prefer         string   `yaml:"prefer"`
+	Results        []CompilerFindResult
+	Rejected       []CompilerFindRejection
+}
+
+func (this *CompilerFindDefinition) searchPaths(defaultPaths []string) []string {
+	paths := []string{}
+
+	for _, findPath := range this.Paths {
+		paths = append(paths, expandUserPath(findPath))
+	}
+
+	// TODO: add the ones defined in this project, if we will ever do that
+	return append(paths, defaultPaths...)
+}
+
+func (this *CompilerFindDefinition) reject(path string, reason string) {
+	this.Rejected = append(this.Rejected, CompilerFindRejection{
+		Path:   path,
+		Reason: reason,
+	})
+}
+
+// Runs the found program to extract its version, e.g. `python3 --version`.
+func (this *CompilerFindDefinition) programVersion(path string) (string, error) {
+	versionCommand := this.VersionCommand
+
+	if len(versionCommand) == 0 {
+		versionCommand = DEFAULT_VERSION_COMMAND
+	}
+
+	versionRegex := this.VersionRegex
+
+	if len(versionRegex) == 0 {
+		versionRegex = DEFAULT_VERSION_REGEX
+	}
+
+	pattern, err := regexp.Compile(versionRegex)
+
+	if err != nil {
+		return "", fmt.Errorf("invalid version_regex `%s`: %s", versionRegex, err.Error())
+	}
+
+	// some programs print their version to stderr
+	output, err := probeCommandOutput(path, strings.Fields(versionCommand)...)
+
+	if err != nil {
+		return "", fmt.Errorf("`%s %s` failed: %s", path, versionCommand, err.Error())
+	}
+
+	submatches := pattern.FindStringSubmatch(string(output))
+
+	if submatches == nil {
+		return "", fmt.Errorf("no version matching `%s` in output of `%s %s`", versionRegex, path, versionCommand)
+	}
+
+	if len(submatches) > 1 {
+		return submatches[1], nil
+	}
+
+	return submatches[0], nil
+}
+
+// Checks the version constraint, if any, and records why a candidate was rejected.
+func (this *CompilerFindDefinition) acceptProgram(path string) (string, bool, error) {
+	if len(this.Version) == 0 {
+		return "", true, nil
+	}
+
+	version, err := this.programVersion(path)
+
+	if err != nil {
+		this.reject(path, err.Error())
+		return "", false, nil
+	}
+
+	ok, err := versionSatisfies(version, this.Version)
+
+	if err != nil {
+		return "", false, err
+	}
+
+	if !ok {
+		this.reject(path, fmt.Sprintf("version %s does not satisfy `%s`", version, this.Version))
+	}
+
+	return version, ok, nil
+}
+
+func (this *CompilerFindDefinition) findFilesystem() ([]string, error) {
+	notFound := []string{}
+	paths := this.searchPaths(strings.Split(os.Getenv("PATH"), ":"))
+
+	for _, file := range this.Names {
+		found := false
+
+		for _, filePath := range paths {
+			fullPath := filepath.Join(filePath, file)
+			_, err := os.Stat(fullPath)
+
+			if err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+
+				return nil, err
+			}
+
+			version, ok, err := this.acceptProgram(fullPath)
+
+			if err != nil {
+				return nil, err
+			}
+
+			if !ok {
+				continue
+			}
+
+			found = true
+			this.Results = append(this.Results, CompilerFindResult{
+				Type:    this.Type,
+				File:    file,
+				Path:    fullPath,
+				Version: version,
+			})
+
+			break
+		}
+
+		if !found {
+			notFound = append(notFound, file)
+		}
+	}
+
+	return notFound, nil
+}
+
+func (this *CompilerFindDefinition) findPkgConfig() ([]string, error) {
+	notFound := []string{}
+
+	for _, name := range this.Names {
+		resolver := &pkgConfigResolver{
+			SearchPaths: this.searchPaths(pkgConfigDirs()),
+		}
+
+		module, err := resolver.resolve(name, this.Version)
+
+		if err != nil {
+			this.reject(name, err.Error())
+			notFound = append(notFound, name)
+			continue
+		}
+
+		this.Results = append(this.Results, CompilerFindResult{
+			Type:    this.Type,
+			File:    name,
+			Path:    module.Path,
+			Version: module.Version,
+			Flags:   resolver.Cflags,
+			Links:   resolver.Libs,
+		})
+	}
+
+	return notFound, nil
+}
+
+func sharedLibraryFileName(name string) string {
+	if targetPlatform() == "darwin" {
+		return fmt.Sprintf("lib%s.dylib", name)
+	}
+
+	// import libraries of DLLs
+	if targetPlatform() == "windows" {
+		return fmt.Sprintf("%s.lib", name)
+	}
+
+	return fmt.Sprintf("lib%s.so", name)
+}
+
+func staticLibraryFileName(name string) string {
+	if targetPlatform() == "windows" {
+		return fmt.Sprintf("%s.lib", name)
+	}
+
+	return fmt.Sprintf("lib%s.a", name)
+}
+
+func (this *CompilerFindDefinition) findLibrary(compiler *Compiler) ([]string, error) {
+	if len(this.Prefer) > 0 && this.Prefer != "shared" && this.Prefer != "static" {
+		return nil, fmt.Errorf("Unsupported library preference `%s`, expected `shared` or `static`!", this.Prefer)
+	}
+
+	notFound := []string{}
+	systemDirs := systemLibraryDirs()
+	paths := this.searchPaths(systemDirs)
+
+	for _, name := range this.Names {
+		fileNames := []string{sharedLibraryFileName(name), staticLibraryFileName(name)}
+
+		if this.Prefer == "static" {
+			fileNames = []string{staticLibraryFileName(name), sharedLibraryFileName(name)}
+		}
+
+		found := false
+
+		for _, fileName := range fileNames {
+			for _, libraryPath := range paths {
+				fullPath := filepath.Join(libraryPath, fileName)
+
+				if _, err := os.Stat(fullPath); err != nil {
+					continue
+				}
+
+				result := CompilerFindResult{
+					Type: this.Type,
+					File: name,
+					Path: fullPath,
+				}
+
+				if filepath.Ext(fileName) == ".a" {
+					// linking the archive directly works regardless of a shared library next to it
+					result.Links = []string{fullPath}
+				} else {
+					// the linker searches its system library directories anyway
+					if !slices.Contains(systemDirs, libraryPath) {
+						result.Links = append(result.Links, fmt.Sprintf("%s%s", compiler.LinkSearchFlag, libraryPath))
+					}
+
+					result.Links = append(result.Links, fmt.Sprintf("%s%s%s", compiler.LibraryFlag, name, compiler.LibraryExtension))
+				}
+
+				this.Results = append(this.Results, result)
+				found = true
+				break
+			}
+
+			if found {
+				break
+			}
+		}
+
+		if !found {
+			notFound = append(notFound, name)
+		}
+	}
+
+	return notFound, nil
+}
+
+func (this *CompilerFindDefinition) findHeader(compiler *Compiler) ([]string, error) {
+	notFound := []string{}
+	systemDirs := systemIncludeDirs()
+	paths := this.searchPaths(systemDirs)
+
+	for _, name := range this.Names {
+		found := false
+
+		for _, includePath := range paths {
+			fullPath := filepath.Join(includePath, name)
+
+			if _, err := os.Stat(fullPath); err != nil {
+				continue
+			}
+
+			result := CompilerFindResult{
+				Type: this.Type,
+				File: name,
+				Path: fullPath,
+			}
+
+			// the compiler searches its system include directories anyway
+			if !slices.Contains(systemDirs, includePath) {
+				result.Flags = []string{compiler.IncludeSearchFlag, includePath}
+			}
+
+			this.Results = append(this.Results, result)
+			found = true
+			break
+		}
+
+		if !found {
+			notFound = append(notFound, name)
+		}
+	}
+
+	return notFound, nil
+}
+
+// Searches for all names and returns the ones which could not be found.
+// Library and header results use the flags of the given compiler family.
+func (this *CompilerFindDefinition) find(compiler *Compiler) ([]string, error) {
+	switch this.Type {
+	case "filesystem":
+		return this.findFilesystem()
+	case "pkgconfig":
+		return this.findPkgConfig()
+	case "library":
+		return this.findLibrary(compiler)
+	case "header":
+		return this.findHeader(compiler)
+	}
+
+	return nil, fmt.Errorf("Unsupported find type `%s`!", this.Type)
 }
@@ -0,0 +1,177 @@
+package gmakec
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"golang.org/x/exp/slices"
+	"gopkg.in/yaml.v2"
+)
+
+const COMPILER_PROBES_FILE string = "compilers.yaml"
+
+var compilerVersionPattern = regexp.MustCompile(DEFAULT_VERSION_REGEX)
+
+// Facts about a compiler executable, cached until the executable changes.
+type CompilerProbe struct {
+	Path        string   `yaml:"path"`
+	ModTime     int64    `yaml:"mod_time"`
+	Size        int64    `yaml:"size"`
+	Version     string   `yaml:"version"`
+	Target      string   `yaml:"target"`
+	CStandard   string   `yaml:"c_standard"`
+	CxxStandard string   `yaml:"cxx_standard"`
+	IncludeDirs []string `yaml:"include_dirs"`
+}
+
+var compilerProbes = map[string]*CompilerProbe{}
+
+func loadCompilerProbes(configureDir string) {
+	content, err := os.ReadFile(filepath.Join(configureDir, COMPILER_PROBES_FILE))
+
+	if err != nil {
+		return
+	}
+
+	probes := []*CompilerProbe{}
+
+	if err := yaml.Unmarshal(content, &probes); err != nil {
+		return
+	}
+
+	for _, probe := range probes {
+		if _, ok := compilerProbes[probe.Path]; !ok {
+			compilerProbes[probe.Path] = probe
+		}
+	}
+}
+
+func saveCompilerProbes(configureDir string) error {
+	probes := []*CompilerProbe{}
+
+	for _, probe := range compilerProbes {
+		probes = append(probes, probe)
+	}
+
+	slices.SortFunc(probes, func(a *CompilerProbe, b *CompilerProbe) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	content, err := yaml.Marshal(probes)
+
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(configureDir, COMPILER_PROBES_FILE), content, 0644)
+}
+
+func (this *CompilerProbe) isUpToDate(info os.FileInfo) bool {
+	return this.ModTime == info.ModTime().UnixMicro() && this.Size == info.Size()
+}
+
+func probeOutput(path string, args ...string) string {
+	// gcc prints the include search list to stderr
+	output, err := probeCommandOutput(path, args...)
+
+	if err != nil {
+		return ""
+	}
+
+	return string(output)
+}
+
+func predefinedMacro(macros string, name string) string {
+	pattern := regexp.MustCompile(fmt.Sprintf(`(?m)^#define %s (.+)$`, regexp.QuoteMeta(name)))
+	submatches := pattern.FindStringSubmatch(macros)
+
+	if submatches == nil {
+		return ""
+	}
+
+	return strings.TrimSpace(submatches[1])
+}
+
+func builtinIncludeDirs(output string) []string {
+	includeDirs := []string{}
+	inSearchList := false
+
+	for _, line := range strings.Split(output, "\n") {
+		switch {
+		case strings.HasPrefix(line, "#include <...> search starts here:"):
+			inSearchList = true
+		case strings.HasPrefix(line, "End of search list."):
+			inSearchList = false
+		case inSearchList:
+			includeDir := strings.TrimSuffix(strings.TrimSpace(line), " (framework directory)")
+			includeDirs = append(includeDirs, filepath.Clean(includeDir))
+		}
+	}
+
+	return includeDirs
+}
+
+// Probes the compiler once, facts a compiler family does not support stay empty.
+func probeCompiler(compiler *Compiler) *CompilerProbe {
+	info, err := os.Stat(compiler.Path)
+
+	if err != nil {
+		return &CompilerProbe{Path: compiler.Path}
+	}
+
+	if probe, ok := compilerProbes[compiler.Path]; ok && probe.isUpToDate(info) {
+		return probe
+	}
+
+	command := compiler.command()
+	probe := &CompilerProbe{
+		Path:    compiler.Path,
+		ModTime: info.ModTime().UnixMicro(),
+		Size:    info.Size(),
+	}
+
+	if version := compilerVersionPattern.FindString(probeOutput(command[0], append(command[1:], "--version")...)); len(version) > 0 {
+		probe.Version = version
+	}
+
+	probe.Target = strings.TrimSpace(probeOutput(command[0], append(command[1:], "-dumpmachine")...))
+
+	cMacros := probeOutput(command[0], append(command[1:], "-dM", "-E", "-x", "c", os.DevNull)...)
+	probe.CStandard = predefinedMacro(cMacros, "__STDC_VERSION__")
+
+	cxxMacros := probeOutput(command[0], append(command[1:], "-dM", "-E", "-x", "c++", os.DevNull)...)
+	probe.CxxStandard = predefinedMacro(cxxMacros, "__cplusplus")
+
+	probe.IncludeDirs = builtinIncludeDirs(probeOutput(command[0], append(command[1:], "-E", "-v", "-x", "c", os.DevNull)...))
+
+	compilerProbes[compiler.Path] = probe
+	return probe
+}
+
+// Variables like COMPILER_VERSION, usable in configure files and action environments.
+func (this *Compiler) variables() map[string]string {
+	variables := map[string]string{
+		"COMPILER_NAME":   this.Name,
+		"COMPILER_FAMILY": this.Family,
+		"COMPILER_PATH":   this.Path,
+	}
+
+	if this.Probe == nil {
+		return variables
+	}
+
+	variables["COMPILER_VERSION"] = this.Probe.Version
+	variables["COMPILER_TARGET"] = this.Probe.Target
+	variables["COMPILER_C_STANDARD"] = this.Probe.CStandard
+	variables["COMPILER_CXX_STANDARD"] = this.Probe.CxxStandard
+	variables["COMPILER_INCLUDE_DIRS"] = strings.Join(this.Probe.IncludeDirs, string(os.PathListSeparator))
+
+	if semver := strings.Split(this.Probe.Version, "."); len(this.Probe.Version) > 0 {
+		variables["COMPILER_VERSION_MAJOR"] = semver[0]
+	}
+
+	return variables
+}
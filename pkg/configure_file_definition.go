@@ -5,15 +5,24 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"regexp"
 	"strings"
 )
 
+var configureVariablePattern = regexp.MustCompile(`\$\{([A-Za-z0-9_]+)\}`)
+
 type ConfigureFile struct {
 	Source      string `yaml:"source"`
 	Destination string `yaml:"destination"`
 }
 
-func (this *ConfigureFile) configureVariable(key string, definitionContext *DefinitionContext) (string, error) {
+func (this *ConfigureFile) configureVariable(
+	key string, definitionContext *DefinitionContext, variables map[string]string,
+) (string, error) {
+	if value, ok := variables[key]; ok {
+		return value, nil
+	}
+
 	// TODO: make this "cmake complete" and probably more fleixble...
 	switch {
 	case key == "PROJECT_VERSION":
@@ -31,7 +40,8 @@ func (this *ConfigureFile) configureVariable(key string, definitionContext *Defi
 	return key, fmt.Errorf("WARNING: Could not find key `%s` to configure file `%s`!\n", key, this.Source)
 }
 
-func (this *ConfigureFile) Execute(definitionContext *DefinitionContext) error {
+// Variables are the ones of the compiler (find results, probed facts) and the checks of the target.
+func (this *ConfigureFile) Execute(definitionContext *DefinitionContext, variables map[string]string) error {
 	source, err := os.Open(this.Source)
 
 	if err != nil {
@@ -60,7 +70,7 @@ func (this *ConfigureFile) Execute(definitionContext *DefinitionContext) error {
 			end := strings.Index(line[start:], "@")
 
 			targetString := line[start : start+end]
-			value, err := this.configureVariable(targetString, definitionContext)
+			value, err := this.configureVariable(targetString, definitionContext, variables)
 
 			if err != nil {
 				log.Printf(err.Error())
@@ -69,6 +79,18 @@ func (this *ConfigureFile) Execute(definitionContext *DefinitionContext) error {
 			line = strings.ReplaceAll(line, fmt.Sprintf("@%s@", targetString), value)
 		}
 
+		line = configureVariablePattern.ReplaceAllStringFunc(line, func(match string) string {
+			key := configureVariablePattern.FindStringSubmatch(match)[1]
+			value, err := this.configureVariable(key, definitionContext, variables)
+
+			if err != nil {
+				log.Printf(err.Error())
+				return match
+			}
+
+			return value
+		})
+
 		destination.WriteString(fmt.Sprintf("%s\n", line))
 	}
 
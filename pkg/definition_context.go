@@ -15,11 +15,13 @@ import (
 )
 
 const CONFIGURE_DIR string = ".gmakec"
+const CONFIGURE_GROUPS_DIR string = "groups"
 
 type DefinitionContext struct {
-	DefinitionPath string
-	Definition     *GlobalDefinition
-	ConfigureDir   string
+	DefinitionPath    string
+	Definition        *GlobalDefinition
+	ConfigureDir      string
+	configuredTargets []Target
 }
 
 func NewDefinitionContext(path string) (*DefinitionContext, error) {
@@ -51,12 +53,32 @@ func NewDefinitionContext(path string) (*DefinitionContext, error) {
 	return defContext, nil
 }
 
+func (this *DefinitionContext) ProjectName() string {
+	if len(this.Definition.Name) > 0 {
+		return this.Definition.Name
+	}
+
+	absolutePath, err := filepath.Abs(this.DefinitionPath)
+
+	if err != nil {
+		return filepath.Base(this.DefinitionPath)
+	}
+
+	return filepath.Base(absolutePath)
+}
+
 func (this *DefinitionContext) Configure(definitionContexts *[]*DefinitionContext, targets ...string) error {
 	graphs := this.Definition.generateDependencyGraphs(targets...)
 	targetGroupMatrix := generateTargetGroupMatrix(graphs)
+	this.configuredTargets = []Target{}
 
-	RemovePath(this.ConfigureDir)
-	if err := os.MkdirAll(this.ConfigureDir, os.ModePerm); err != nil {
+	// the compiler probes are kept across configure runs
+	groupsDir := filepath.Join(this.ConfigureDir, CONFIGURE_GROUPS_DIR)
+	loadCompilerProbes(this.ConfigureDir)
+	loadCheckResults(this.ConfigureDir)
+
+	RemovePath(groupsDir)
+	if err := os.MkdirAll(groupsDir, os.ModePerm); err != nil {
 		return err
 	}
 
@@ -71,7 +93,9 @@ func (this *DefinitionContext) Configure(definitionContexts *[]*DefinitionContex
 			return err
 		}
 
-		filePath := fmt.Sprintf("%s/%d", this.ConfigureDir, index)
+		this.configuredTargets = append(this.configuredTargets, targets...)
+
+		filePath := fmt.Sprintf("%s/%d", groupsDir, index)
 		file, err := os.Create(filePath)
 
 		if err != nil {
@@ -81,28 +105,127 @@ func (this *DefinitionContext) Configure(definitionContexts *[]*DefinitionContex
 		defer file.Close()
 
 		for _, target := range targets {
-			contents, err := target.buildCommand()
+			lines, err := target.buildCommands()
 
 			if err != nil {
 				return err
 			}
 
-			_, err = file.WriteString(fmt.Sprintf("%s\n", contents))
+			for _, line := range lines {
+				_, err = file.WriteString(fmt.Sprintf("%s\n", line))
 
-			if err != nil {
-				return err
+				if err != nil {
+					return err
+				}
 			}
 		}
 	}
 
+	if err := saveProfile(this.ConfigureDir); err != nil {
+		return err
+	}
+
+	if err := saveCheckResults(this.ConfigureDir); err != nil {
+		return err
+	}
+
+	return saveCompilerProbes(this.ConfigureDir)
+}
+
+// The configured target has its compiler sanitized and hook refs merged.
+func (this *DefinitionContext) configuredTarget(targetIndex int) *Target {
+	for index := range this.configuredTargets {
+		if this.configuredTargets[index].Index == targetIndex {
+			return &this.configuredTargets[index]
+		}
+	}
+
 	return nil
 }
 
+// Runs the build steps of a target which are not up to date and returns whether any step ran.
+func (this *DefinitionContext) buildTarget(target *Target, shellCommands [][]string, verbose bool) (bool, error) {
+	steps := target.buildSteps()
+
+	if len(steps) != len(shellCommands) {
+		return false, fmt.Errorf("Configuration of target %d is out of date, please reconfigure!", target.Index)
+	}
+
+	targetDef := target.Definition
+	built := false
+
+	for index, shellCommand := range shellCommands {
+		if shellCommand[1] == "skip" {
+			// targets built before in this group (e.g. generators) might have changed its inputs
+			rebuild, err := steps[index].needsRebuild()
+
+			if err != nil {
+				return false, err
+			}
+
+			if !rebuild {
+				continue
+			}
+		}
+
+		if !built {
+			built = true
+
+			if err := targetDef.executeHooks("pre-build", this.DefinitionPath); err != nil {
+				return false, err
+			}
+
+			if err := target.generateSources(this.DefinitionPath); err != nil {
+				return false, err
+			}
+		}
+
+		for _, output := range steps[index].Outputs {
+			outputDir := filepath.Dir(filepath.Join(this.DefinitionPath, output))
+
+			if err := os.MkdirAll(outputDir, os.ModePerm); err != nil {
+				return false, err
+			}
+		}
+
+		if targetDef.isCommand() {
+			fmt.Printf("[build] Generating %v\n", targetDef.Outputs)
+
+			if err := targetDef.runCommand(this.DefinitionPath); err != nil {
+				return false, err
+			}
+
+			continue
+		}
+
+		// appended, so it is passed to the compiler and not to its launcher
+		if verbose {
+			shellCommand = append(shellCommand, "-v")
+		}
+
+		command := exec.Command(shellCommand[2], shellCommand[3:]...)
+
+		if err := executeCommand(command, this.DefinitionPath); err != nil {
+			return false, err
+		}
+	}
+
+	if built {
+		if err := targetDef.executeHooks("post-build", this.DefinitionPath); err != nil {
+			return false, err
+		}
+	}
+
+	return built, nil
+}
+
 func (this *DefinitionContext) Build(verbose bool) error {
 	var wg sync.WaitGroup
 
-	err := filepath.Walk(this.ConfigureDir, func(name string, info os.FileInfo, err error) error {
-		if name == this.ConfigureDir && info != nil && info.IsDir() {
+	groupsDir := filepath.Join(this.ConfigureDir, CONFIGURE_GROUPS_DIR)
+
+	err := filepath.Walk(groupsDir, func(name string, info os.FileInfo, err error) error {
+		if name == groupsDir && info != nil && info.IsDir() {
 			return nil
 		}
 
@@ -117,49 +240,53 @@ func (this *DefinitionContext) Build(verbose bool) error {
 		go func(lines []string) {
 			defer wg.Done()
 
-			for _, line := range lines {
-				if len(line) == 0 {
-					continue
+			var target *Target
+			shellCommands := [][]string{}
+
+			buildTarget := func() {
+				if target == nil {
+					return
 				}
 
-				shellCommand := strings.Split(line, " ")
-				targetIndex, err := strconv.Atoi(shellCommand[0])
+				built, err := this.buildTarget(target, shellCommands, verbose)
 
 				if err != nil {
 					log.Fatal(err)
 				}
 
-				if shellCommand[1] == "skip" {
-					fmt.Printf("[build] Skipping target %d of target group %s\n", targetIndex, filepath.Base(name))
-					continue
+				if !built {
+					fmt.Printf("[build] Skipping target %d of target group %s\n", target.Index, filepath.Base(name))
 				}
+			}
 
-				targetDef := this.Definition.Targets[targetIndex]
-
-				if err := targetDef.executeHooks("pre-build", this.DefinitionPath); err != nil {
-					log.Fatal(err)
+			// consecutive lines with the same target index are the build steps of one target
+			for _, line := range lines {
+				if len(line) == 0 {
+					continue
 				}
 
-				outputDir := filepath.Dir(filepath.Join(this.DefinitionPath, targetDef.Output))
+				shellCommand := strings.Split(line, " ")
+				targetIndex, err := strconv.Atoi(shellCommand[0])
 
-				if err := os.MkdirAll(outputDir, os.ModePerm); err != nil {
+				if err != nil {
 					log.Fatal(err)
 				}
 
-				if verbose {
-					shellCommand = slices.Insert(shellCommand, 3, "-v")
-				}
+				if target == nil || target.Index != targetIndex {
+					buildTarget()
 
-				command := exec.Command(shellCommand[2], shellCommand[3:]...)
+					target = this.configuredTarget(targetIndex)
+					shellCommands = [][]string{}
 
-				if err := executeCommand(command, this.DefinitionPath); err != nil {
-					log.Fatal(err)
+					if target == nil {
+						log.Fatalf("Target %d of target group %s is not configured!", targetIndex, filepath.Base(name))
+					}
 				}
 
-				if err := targetDef.executeHooks("post-build", this.DefinitionPath); err != nil {
-					log.Fatal(err)
-				}
+				shellCommands = append(shellCommands, shellCommand)
 			}
+
+			buildTarget()
 		}(strings.Split(string(bytes), "\n"))
 		return nil
 	})
@@ -167,3 +294,119 @@ func (this *DefinitionContext) Build(verbose bool) error {
 	wg.Wait()
 	return err
 }
+
+// Collects the tests of this definition matching the filter, including targets marked as test.
+func (this *DefinitionContext) TestCases(filter string, definitionContexts *[]*DefinitionContext) ([]TestCase, error) {
+	testDefs := []TestDefinition{}
+
+	for _, targetDef := range this.Definition.Targets {
+		if targetDef.Test {
+			if len(targetDef.Name) == 0 {
+				return nil, fmt.Errorf("Targets marked as test need to have the field `name` set!")
+			}
+
+			testDefs = append(testDefs, TestDefinition{Target: targetDef.Name})
+		}
+	}
+
+	testDefs = append(testDefs, this.Definition.Tests...)
+	testCases := []TestCase{}
+
+	for index := range testDefs {
+		testCase, err := testDefs[index].testCase(this, definitionContexts)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if testCase.matches(filter) {
+			testCases = append(testCases, *testCase)
+		}
+	}
+
+	return testCases, nil
+}
+
+// Prints the commands a build would run without running them.
+func (this *DefinitionContext) DryRun() error {
+	fmt.Printf("# %s\n", this.DefinitionPath)
+	rebuiltOutputs := []string{}
+
+	for index := range this.configuredTargets {
+		target := &this.configuredTargets[index]
+		steps := target.buildSteps()
+
+		for stepIndex := range steps {
+			rebuild, err := steps[stepIndex].needsRebuild()
+
+			if err != nil {
+				return err
+			}
+
+			// steps depending on outputs of previous steps would be rebuilt as well
+			dependsOnRebuilt := slices.ContainsFunc(steps[stepIndex].Inputs, func(input string) bool {
+				return slices.Contains(rebuiltOutputs, input)
+			})
+
+			if !rebuild && !dependsOnRebuilt {
+				continue
+			}
+
+			rebuiltOutputs = append(rebuiltOutputs, steps[stepIndex].Outputs...)
+
+			if target.Definition.isCommand() {
+				fmt.Println(target.Definition.Command)
+			} else {
+				fmt.Println(strings.Join(steps[stepIndex].Command, " "))
+			}
+		}
+	}
+
+	return nil
+}
+
+func (this *DefinitionContext) Clean() {
+	for _, targetDef := range this.Definition.Targets {
+		if targetDef.isCommand() {
+			// outputs might be generated next to sources, so only remove the files themselves
+			for _, output := range targetDef.Outputs {
+				RemovePath(filepath.Join(this.DefinitionPath, output))
+			}
+
+			continue
+		}
+
+		outputDir := filepath.Join(this.DefinitionPath, filepath.Dir(targetDef.Output))
+		RemovePath(outputDir)
+	}
+
+	RemovePath(this.ConfigureDir)
+}
+
+func (this *DefinitionContext) Install(installer *Installer, definitionContexts *[]*DefinitionContext) error {
+	for index := range this.configuredTargets {
+		target := &this.configuredTargets[index]
+
+		for _, installDef := range target.Definition.Install {
+			var err error
+
+			if len(installDef.Files) == 0 {
+				err = installDef.installOutput(installer, target, this)
+			} else {
+				err = installDef.install(installer, this, definitionContexts)
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, installDef := range this.Definition.Install {
+		if err := installDef.install(installer, this, definitionContexts); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -51,20 +51,12 @@ func findRefTargetStringValue(
 	return fieldValue, nil
 }
 
-func findRefTargetStringArrayValue(
-	refString string, targetDefinition *TargetDefinition, definitionContexts *[]*DefinitionContext,
-) ([]string, error) {
+func findRefTargetValues(refString string, definitionContexts *[]*DefinitionContext) ([]string, error) {
 	fieldName, refContext, refTarget, err := findRefData(refString, definitionContexts)
 
-	if err != nil {
-		return nil, nil
-	}
-
-	fieldValue, err := refTarget.fieldStringArrayValue(fieldName, refContext)
-
 	if err != nil {
 		return nil, err
 	}
 
-	return fieldValue, nil
+	return refTarget.fieldValues(fieldName, refContext)
 }
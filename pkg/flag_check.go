@@ -0,0 +1,85 @@
+package gmakec
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// Compiles an empty program with the flag and the flags of the compiler definition, e.g. the toolchain
+// ones. Warnings as errors make unknown warning flags fail.
+func (this *CompilerDefinition) supportsFlag(flag string, language string) bool {
+	checkDir, err := os.MkdirTemp("", "gmakec-flag-check")
+
+	if err != nil {
+		return false
+	}
+
+	defer RemovePath(checkDir)
+
+	source := filepath.Join(checkDir, fmt.Sprintf("check%s", languageExtensions[language][0]))
+
+	if err := os.WriteFile(source, []byte("int main(void) { return 0; }\n"), 0644); err != nil {
+		return false
+	}
+
+	// gcc silently accepts unknown -Wno-* flags, their positive form is checked instead
+	if strings.HasPrefix(flag, "-Wno-") {
+		flag = fmt.Sprintf("-W%s", strings.TrimPrefix(flag, "-Wno-"))
+	}
+
+	compiler := this.Object
+	command := append(compiler.command(), this.Flags...)
+
+	if len(compiler.WarningsAsErrorsFlag) > 0 {
+		command = append(command, compiler.WarningsAsErrorsFlag)
+	}
+
+	command = append(command, flag, compiler.CompileOnlyFlag, source)
+	command = append(command, compiler.objectOutputFlags(filepath.Join(checkDir, "check"))...)
+
+	process := exec.Command(command[0], command[1:]...)
+	process.Dir = checkDir
+	return process.Run() == nil
+}
+
+// The check flags the compiler supports for the language, unsupported ones are dropped with a notice.
+// Results are cached until the compiler executable changes, e.g. on an upgrade.
+func (this *CompilerDefinition) supportedFlags(language string) []string {
+	flags := []string{}
+	identity := append(append(this.Object.command(), this.Flags...), language)
+
+	// probes of executables which could not be inspected are empty
+	if probe := this.Object.Probe; probe != nil {
+		identity = append(identity, fmt.Sprintf("%d", probe.ModTime), fmt.Sprintf("%d", probe.Size), probe.Version)
+	}
+
+	// assembly sources cannot hold the probe program
+	if language == LANGUAGE_ASM {
+		language = LANGUAGE_C
+	}
+
+	for _, flag := range this.CheckFlags {
+		key := fmt.Sprintf("%x", sha1.Sum([]byte(strings.Join(append(slices.Clone(identity), flag), " "))))
+		result, cached := checkResults[key]
+
+		if !cached {
+			result = &CheckResult{Success: this.supportsFlag(flag, language), Value: flag}
+			checkResults[key] = result
+		}
+
+		if !result.Success {
+			fmt.Printf("[check] Compiler flag %s is not supported by %s, dropping it\n", flag, this.Object.Name)
+			continue
+		}
+
+		flags = append(flags, flag)
+	}
+
+	return flags
+}
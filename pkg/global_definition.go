@@ -2,13 +2,13 @@ package gmakec
 
 import (
 	"fmt"
-	"runtime"
 	"strings"
 
 	"golang.org/x/exp/slices"
 )
 
 type GlobalDefinition struct {
+	Name         string               `yaml:"name"`
 	Description  string               `yaml:"description"` // unused atm
 	Version      string               `yaml:"version"`
 	Compilers    []CompilerDefinition `yaml:"compilers"`
@@ -16,6 +16,13 @@ type GlobalDefinition struct {
 	Hooks        []HookDefinition     `yaml:"hooks"`
 	Targets      []TargetDefinition   `yaml:"targets"`
 	Imports      []string             `yaml:"imports"`
+	Install      []InstallDefinition  `yaml:"install"`
+	Rules        []RuleDefinition     `yaml:"rules"`
+	Tests        []TestDefinition     `yaml:"tests"`
+	Families     []Compiler           `yaml:"compiler_families"`
+	Profiles     []Profile            `yaml:"profiles"`
+	Profile      *Profile             `yaml:"-"`
+	families     []*Compiler
 	VersionMajor string
 	VersionMinor string
 	VersionPatch string
@@ -54,11 +61,26 @@ func (this *GlobalDefinition) sanitizeTargets(definitionContext *DefinitionConte
 	t := make([]TargetDefinition, 0)
 
 	for index, targetDef := range this.Targets {
-		if len(targetDef.Platform) > 0 && runtime.GOOS != targetDef.Platform {
+		if len(targetDef.Platform) > 0 && targetPlatform() != targetDef.Platform {
 			continue
 		}
 
-		if len(targetDef.Output) == 0 {
+		if targetDef.isCommand() {
+			if len(targetDef.Command) == 0 || len(targetDef.Outputs) == 0 {
+				return fmt.Errorf(
+					"Command target of definition path `%s` and index %d needs to have the fields `command` and `outputs` set!",
+					definitionContext.DefinitionPath,
+					index,
+				)
+			}
+		} else if len(targetDef.Type) > 0 {
+			return fmt.Errorf(
+				"Target of definition path `%s` and index %d has unsupported type `%s`!",
+				definitionContext.DefinitionPath,
+				index,
+				targetDef.Type,
+			)
+		} else if len(targetDef.Output) == 0 {
 			return fmt.Errorf(
 				"Target of definition path `%s` and index %d has no output!",
 				definitionContext.DefinitionPath,
@@ -96,6 +118,33 @@ func (this *GlobalDefinition) sanitizeCompilers() error {
 	return nil
 }
 
+// Families of a definition are not visible to its imports or the projects importing it.
+func (this *GlobalDefinition) sanitizeCompilerFamilies() error {
+	var err error
+	this.families = compilers
+
+	for _, family := range this.Families {
+		if this.families, err = registerCompilerFamily(this.families, family); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (this *GlobalDefinition) sanitizeRules() error {
+	for index, rule := range this.Rules {
+		if len(rule.Extension) == 0 || len(rule.Outputs) == 0 || len(rule.Command) == 0 {
+			return fmt.Errorf(
+				"Rule of name `%s` (index %d) needs to have the fields `extension`, `outputs` and `command` set!",
+				rule.Name, index,
+			)
+		}
+	}
+
+	return nil
+}
+
 func (this *GlobalDefinition) sanitize(definitionContext *DefinitionContext) error {
 	if err := this.sanitizeVersion(); err != nil {
 		return err
@@ -105,10 +154,18 @@ func (this *GlobalDefinition) sanitize(definitionContext *DefinitionContext) err
 		return err
 	}
 
+	if err := this.sanitizeCompilerFamilies(); err != nil {
+		return err
+	}
+
 	if err := this.sanitizeCompilers(); err != nil {
 		return err
 	}
 
+	if err := this.sanitizeRules(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
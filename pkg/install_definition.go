@@ -0,0 +1,248 @@
+package gmakec
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/yargevad/filepathx"
+	"golang.org/x/exp/slices"
+)
+
+var headerExtensions = []string{".h", ".hh", ".hpp", ".hxx", ".inl"}
+
+type InstallDefinition struct {
+	Files       []string `yaml:"files"`
+	Destination string   `yaml:"destination"`
+	Mode        string   `yaml:"mode"`
+}
+
+type Installer struct {
+	Prefix  string
+	DestDir string
+}
+
+func (this *Installer) installDir(destination string) string {
+	return filepath.Join(this.DestDir, this.Prefix, destination)
+}
+
+func defaultFileDestination(path string) string {
+	if slices.Contains(headerExtensions, filepath.Ext(path)) {
+		return "include"
+	}
+
+	return "share"
+}
+
+func defaultOutputDestination(path string) string {
+	switch filepath.Ext(path) {
+	case ".a", ".lib", ".o", ".obj":
+		return "lib"
+	case ".dll", ".exe":
+		return "bin"
+	}
+
+	if isSharedLibrary(path) {
+		return "lib"
+	}
+
+	return "bin"
+}
+
+func defaultOutputMode(path string) os.FileMode {
+	switch filepath.Ext(path) {
+	case ".a", ".lib", ".o", ".obj":
+		return 0644
+	}
+
+	return 0755
+}
+
+func (this *InstallDefinition) fileMode(defaultMode os.FileMode) (os.FileMode, error) {
+	if len(this.Mode) == 0 {
+		return defaultMode, nil
+	}
+
+	mode, err := strconv.ParseUint(this.Mode, 8, 32)
+
+	if err != nil {
+		return 0, fmt.Errorf("Invalid install mode `%s`, expected an octal number like `0644`!", this.Mode)
+	}
+
+	return os.FileMode(mode), nil
+}
+
+func (this *InstallDefinition) resolveFiles(
+	definitionContext *DefinitionContext, definitionContexts *[]*DefinitionContext,
+) ([]string, error) {
+	files := []string{}
+
+	for _, file := range this.Files {
+		if strings.Contains(file, ":") {
+			refValues, err := findRefTargetValues(file, definitionContexts)
+
+			if err != nil {
+				return nil, err
+			}
+
+			files = append(files, refValues...)
+		} else if strings.Contains(file, "*") {
+			globbed, err := filepathx.Glob(filepath.Join(definitionContext.DefinitionPath, file))
+
+			if err != nil {
+				return nil, err
+			}
+
+			files = append(files, globbed...)
+		} else {
+			files = append(files, filepath.Join(definitionContext.DefinitionPath, file))
+		}
+	}
+
+	return files, nil
+}
+
+func (this *InstallDefinition) install(
+	installer *Installer, definitionContext *DefinitionContext, definitionContexts *[]*DefinitionContext,
+) error {
+	if len(this.Files) == 0 {
+		return fmt.Errorf(
+			"Install definition of definition path `%s` needs to have the field `files` set!",
+			definitionContext.DefinitionPath,
+		)
+	}
+
+	files, err := this.resolveFiles(definitionContext, definitionContexts)
+
+	if err != nil {
+		return err
+	}
+
+	mode, err := this.fileMode(0644)
+
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		destination := this.Destination
+
+		if len(destination) == 0 {
+			destination = defaultFileDestination(file)
+		}
+
+		if err := installPath(file, installer.installDir(destination), mode); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (this *InstallDefinition) installOutput(
+	installer *Installer, target *Target, definitionContext *DefinitionContext,
+) error {
+	output := filepath.Join(definitionContext.DefinitionPath, target.Definition.Output)
+	destination := this.Destination
+
+	if len(destination) == 0 {
+		destination = defaultOutputDestination(output)
+	}
+
+	mode, err := this.fileMode(defaultOutputMode(output))
+
+	if err != nil {
+		return err
+	}
+
+	installedPath := filepath.Join(installer.installDir(destination), filepath.Base(output))
+
+	if len(target.RPaths) == 0 {
+		return installFile(output, installedPath, mode)
+	}
+
+	// relink to replace the build tree RPATH with the install RPATH
+	if err := os.MkdirAll(filepath.Dir(installedPath), 0755); err != nil {
+		return err
+	}
+
+	absolutePath, err := filepath.Abs(installedPath)
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("[install] Relinking %s\n", installedPath)
+	shellCommand := target.linkCommand(absolutePath, target.Definition.RPath.Install)
+	command := exec.Command(shellCommand[0], shellCommand[1:]...)
+
+	if err := executeCommand(command, definitionContext.DefinitionPath); err != nil {
+		return err
+	}
+
+	return os.Chmod(installedPath, mode)
+}
+
+func installPath(path string, installDir string, mode os.FileMode) error {
+	info, err := os.Stat(path)
+
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		return installFile(path, filepath.Join(installDir, filepath.Base(path)), mode)
+	}
+
+	parentDir := filepath.Dir(path)
+
+	return filepath.Walk(path, func(name string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		relativePath, err := filepath.Rel(parentDir, name)
+
+		if err != nil {
+			return err
+		}
+
+		return installFile(name, filepath.Join(installDir, relativePath), mode)
+	})
+}
+
+func installFile(source string, destination string, mode os.FileMode) error {
+	fmt.Printf("[install] Installing %s\n", destination)
+
+	if err := os.MkdirAll(filepath.Dir(destination), 0755); err != nil {
+		return err
+	}
+
+	sourceFile, err := os.Open(source)
+
+	if err != nil {
+		return err
+	}
+
+	defer sourceFile.Close()
+	RemovePath(destination)
+
+	destinationFile, err := os.OpenFile(destination, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+
+	if err != nil {
+		return err
+	}
+
+	defer destinationFile.Close()
+
+	if _, err := io.Copy(destinationFile, sourceFile); err != nil {
+		return err
+	}
+
+	// the mode passed to OpenFile is subject to the umask
+	return os.Chmod(destination, mode)
+}
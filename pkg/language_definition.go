@@ -0,0 +1,100 @@
+package gmakec
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"golang.org/x/exp/slices"
+)
+
+const (
+	LANGUAGE_C   string = "c"
+	LANGUAGE_CXX string = "cxx"
+	LANGUAGE_ASM string = "asm"
+)
+
+var languageExtensions = map[string][]string{
+	LANGUAGE_C:   {".c"},
+	LANGUAGE_CXX: {".cc", ".cpp", ".cxx", ".c++", ".C"},
+	LANGUAGE_ASM: {".s", ".S", ".asm"},
+}
+
+type LanguageCompilersDefinition struct {
+	C   CompilerDefinition `yaml:"c"`
+	Cxx CompilerDefinition `yaml:"cxx"`
+	Asm CompilerDefinition `yaml:"asm"`
+}
+
+func sourceLanguage(source string) (string, error) {
+	for language, extensions := range languageExtensions {
+		if slices.Contains(extensions, filepath.Ext(source)) {
+			return language, nil
+		}
+	}
+
+	return "", fmt.Errorf("Could not determine the language of source `%s`!", source)
+}
+
+func isCompilerDefined(compilerDefinition *CompilerDefinition) bool {
+	return len(compilerDefinition.Ref) > 0 || len(compilerDefinition.Path) > 0
+}
+
+func (this *LanguageCompilersDefinition) definitions() map[string]*CompilerDefinition {
+	return map[string]*CompilerDefinition{
+		LANGUAGE_C:   &this.C,
+		LANGUAGE_CXX: &this.Cxx,
+		LANGUAGE_ASM: &this.Asm,
+	}
+}
+
+func (this *LanguageCompilersDefinition) defined() bool {
+	for _, compilerDef := range this.definitions() {
+		if isCompilerDefined(compilerDef) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Sanitizes the defined compilers. Assembly falls back to the C compiler driver.
+func (this *LanguageCompilersDefinition) sanitize(
+	definitionContext *DefinitionContext,
+) (map[string]*CompilerDefinition, error) {
+	languages := map[string]*CompilerDefinition{}
+
+	for language, compilerDef := range this.definitions() {
+		if !isCompilerDefined(compilerDef) {
+			continue
+		}
+
+		sanitized, err := compilerDef.sanitize(definitionContext)
+
+		if err != nil {
+			return nil, err
+		}
+
+		sanitized.Flags = append(sanitized.Flags, sanitized.supportedFlags(language)...)
+
+		languages[language] = sanitized
+	}
+
+	if _, ok := languages[LANGUAGE_ASM]; !ok {
+		if cCompilerDef, ok := languages[LANGUAGE_C]; ok {
+			languages[LANGUAGE_ASM] = cCompilerDef
+		}
+	}
+
+	return languages, nil
+}
+
+// The compiler of the first defined language, used for target wide flags like defines.
+func primaryLanguageCompiler(languages map[string]*CompilerDefinition) *CompilerDefinition {
+	for _, language := range []string{LANGUAGE_C, LANGUAGE_CXX, LANGUAGE_ASM} {
+		if compilerDef, ok := languages[language]; ok {
+			return compilerDef
+		}
+	}
+
+	return nil
+}
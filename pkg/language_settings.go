@@ -0,0 +1,218 @@
+package gmakec
+
+import (
+	"fmt"
+	"regexp"
+
+	"golang.org/x/exp/slices"
+)
+
+const (
+	WARNINGS_ALL      string = "all"
+	WARNINGS_EXTRA    string = "extra"
+	WARNINGS_PEDANTIC string = "pedantic"
+	WARNINGS_ERROR    string = "error"
+)
+
+var warningLevels = []string{WARNINGS_ALL, WARNINGS_EXTRA, WARNINGS_PEDANTIC, WARNINGS_ERROR}
+
+// Warning level flags like /W3 and /W4 override each other.
+var warningLevelFlagPattern = regexp.MustCompile(`^[-/]W[0-9]$`)
+
+// Standards by the value of __STDC_VERSION__ and __cplusplus, to spell the default standard of a compiler.
+var probedStandards = map[string]map[string]string{
+	LANGUAGE_C: {
+		"199901L": "99",
+		"201112L": "11",
+		"201710L": "17",
+		"202311L": "23",
+	},
+	LANGUAGE_CXX: {
+		"199711L": "98",
+		"201103L": "11",
+		"201402L": "14",
+		"201703L": "17",
+		"202002L": "20",
+		"202302L": "23",
+	},
+}
+
+// Language standard and warnings of targets and compiler definitions, spelled for the compiler family.
+type LanguageSettings struct {
+	CStandard   string `yaml:"c_standard"`
+	CxxStandard string `yaml:"cxx_standard"`
+	Extensions  *bool  `yaml:"extensions"`
+	Warnings    string `yaml:"warnings"`
+}
+
+// The settings with the ones set by the override replacing them.
+func (this LanguageSettings) merged(override LanguageSettings) LanguageSettings {
+	if len(override.CStandard) > 0 {
+		this.CStandard = override.CStandard
+	}
+
+	if len(override.CxxStandard) > 0 {
+		this.CxxStandard = override.CxxStandard
+	}
+
+	if override.Extensions != nil {
+		this.Extensions = override.Extensions
+	}
+
+	if len(override.Warnings) > 0 {
+		this.Warnings = override.Warnings
+	}
+
+	return this
+}
+
+func (this *LanguageSettings) sanitize() error {
+	if len(this.Warnings) > 0 && !slices.Contains(warningLevels, this.Warnings) {
+		return fmt.Errorf("Unsupported warnings `%s`, supported are %v!", this.Warnings, warningLevels)
+	}
+
+	return nil
+}
+
+// The standard the compiler uses without any flag, as probed.
+func defaultStandard(compiler *Compiler, language string) string {
+	if compiler.Probe == nil {
+		return ""
+	}
+
+	if language == LANGUAGE_CXX {
+		return probedStandards[language][compiler.Probe.CxxStandard]
+	}
+
+	return probedStandards[language][compiler.Probe.CStandard]
+}
+
+// E.g. -std=gnu11 or /std:c++20, extensions are enabled unless disabled explicitly.
+func (this *LanguageSettings) standardFlags(compiler *Compiler, language string) ([]string, error) {
+	flags := []string{}
+	standard := this.CStandard
+	prefix := compiler.CStandardPrefix
+	extensionsPrefix := compiler.ExtensionsCStandardPrefix
+
+	if language == LANGUAGE_CXX {
+		standard = this.CxxStandard
+		prefix = compiler.CxxStandardPrefix
+		extensionsPrefix = compiler.ExtensionsCxxStandardPrefix
+	}
+
+	extensions := this.Extensions == nil || *this.Extensions
+
+	// GNU dialects are disabled by the strict spelling of the default standard, e.g. -std=c17 instead of gnu17
+	if !extensions && len(standard) == 0 && len(extensionsPrefix) > 0 {
+		if standard = defaultStandard(compiler, language); len(standard) == 0 {
+			return nil, fmt.Errorf(
+				"Could not determine the default %s standard of compiler `%s` to disable extensions, please set it explicitly!",
+				language, compiler.Name,
+			)
+		}
+	}
+
+	if len(standard) > 0 && len(compiler.StandardFlag) > 0 {
+		if extensions && len(extensionsPrefix) > 0 {
+			prefix = extensionsPrefix
+		}
+
+		flags = append(flags, fmt.Sprintf("%s%s%s", compiler.StandardFlag, prefix, standard))
+	}
+
+	if !extensions && len(compiler.NoExtensionsFlag) > 0 {
+		flags = append(flags, compiler.NoExtensionsFlag)
+	}
+
+	return flags, nil
+}
+
+// The warning level turns on the lower ones as well, e.g. extra includes all.
+// Of multiple warning level flags like /W3 and /W4 only the highest one is kept.
+func (this *LanguageSettings) warningFlags(compiler *Compiler) []string {
+	flags := []string{}
+
+	if len(this.Warnings) == 0 {
+		return flags
+	}
+
+	for _, warnings := range warningLevels[:slices.Index(warningLevels, this.Warnings)+1] {
+		for _, flag := range compiler.WarningFlags[warnings] {
+			if warningLevelFlagPattern.MatchString(flag) {
+				flags = slices.DeleteFunc(flags, warningLevelFlagPattern.MatchString)
+			}
+
+			if !slices.Contains(flags, flag) {
+				flags = append(flags, flag)
+			}
+		}
+	}
+
+	return flags
+}
+
+func (this *TargetDefinition) languageSettings(compilerDef *CompilerDefinition) (LanguageSettings, error) {
+	settings := compilerDef.LanguageSettings.merged(this.LanguageSettings)
+	return settings, settings.sanitize()
+}
+
+// Standard flags are added before the checks run, so they probe the same language dialect.
+func (this *TargetDefinition) applyStandards(languages map[string]*CompilerDefinition) error {
+	if len(languages) == 0 {
+		settings, err := this.languageSettings(&this.Compiler)
+
+		if err != nil {
+			return err
+		}
+
+		flags, err := settings.standardFlags(this.Compiler.Object, this.language())
+
+		if err != nil {
+			return err
+		}
+
+		this.Compiler.Flags = append(this.Compiler.Flags, flags...)
+		return nil
+	}
+
+	for language, compilerDef := range languages {
+		// assembly has no standard, it might share the compiler of C as well
+		if language == LANGUAGE_ASM {
+			continue
+		}
+
+		settings, err := this.languageSettings(compilerDef)
+
+		if err != nil {
+			return err
+		}
+
+		flags, err := settings.standardFlags(compilerDef.Object, language)
+
+		if err != nil {
+			return err
+		}
+
+		compilerDef.Flags = append(slices.Clone(compilerDef.Flags), flags...)
+	}
+
+	return nil
+}
+
+// Warning flags are added after the checks ran, warnings as errors would fail them.
+func (this *TargetDefinition) applyWarnings(languages map[string]*CompilerDefinition) {
+	if len(languages) == 0 {
+		settings, _ := this.languageSettings(&this.Compiler)
+		this.Compiler.Flags = append(this.Compiler.Flags, settings.warningFlags(this.Compiler.Object)...)
+		return
+	}
+
+	for language, compilerDef := range languages {
+		if language == LANGUAGE_ASM && compilerDef == languages[LANGUAGE_C] {
+			continue
+		}
+
+		settings, _ := this.languageSettings(compilerDef)
+		compilerDef.Flags = append(slices.Clone(compilerDef.Flags), settings.warningFlags(compilerDef.Object)...)
+	}
+}
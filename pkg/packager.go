@@ -0,0 +1,286 @@
+package gmakec
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const PACKAGE_MANIFEST string = "MANIFEST.sha256"
+
+type Packager struct {
+	Name       string
+	Version    string
+	Formats    []string
+	Strip      bool
+	StagingDir string
+	OutputDir  string
+}
+
+func (this *Packager) baseName() string {
+	return fmt.Sprintf("%s-%s", this.Name, this.Version)
+}
+
+func (this *Packager) collectFiles() ([]string, error) {
+	files := []string{}
+
+	err := filepath.Walk(this.StagingDir, func(name string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		relativePath, err := filepath.Rel(this.StagingDir, name)
+
+		if err != nil {
+			return err
+		}
+
+		files = append(files, filepath.ToSlash(relativePath))
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+func isELFFile(path string) bool {
+	file, err := os.Open(path)
+
+	if err != nil {
+		return false
+	}
+
+	defer file.Close()
+	magic := make([]byte, 4)
+
+	if _, err := io.ReadFull(file, magic); err != nil {
+		return false
+	}
+
+	return bytes.Equal(magic, []byte("\x7fELF"))
+}
+
+func (this *Packager) stripBinaries(files []string) error {
+	for _, file := range files {
+		path := filepath.Join(this.StagingDir, file)
+
+		if !isELFFile(path) {
+			continue
+		}
+
+		fmt.Printf("[package] Stripping %s\n", file)
+		command := exec.Command(stripProgram(), "--strip-unneeded", path)
+
+		if err := executeCommand(command, this.StagingDir); err != nil {
+			return fmt.Errorf("Could not strip `%s`: %s", file, err.Error())
+		}
+	}
+
+	return nil
+}
+
+func (this *Packager) writeManifest(files []string) (string, error) {
+	manifest := strings.Builder{}
+
+	for _, file := range files {
+		contents, err := os.ReadFile(filepath.Join(this.StagingDir, file))
+
+		if err != nil {
+			return "", err
+		}
+
+		manifest.WriteString(fmt.Sprintf("%x  %s\n", sha256.Sum256(contents), file))
+	}
+
+	manifestPath := filepath.Join(this.OutputDir, fmt.Sprintf("%s.sha256", this.baseName()))
+
+	// one copy ships inside the archives, one is kept next to them
+	for _, path := range []string{filepath.Join(this.StagingDir, PACKAGE_MANIFEST), manifestPath} {
+		if err := os.WriteFile(path, []byte(manifest.String()), 0644); err != nil {
+			return "", err
+		}
+	}
+
+	return manifestPath, nil
+}
+
+func (this *Packager) writeTarGz(files []string, path string) error {
+	archive, err := os.Create(path)
+
+	if err != nil {
+		return err
+	}
+
+	gzipWriter := gzip.NewWriter(archive)
+	tarWriter := tar.NewWriter(gzipWriter)
+	err = this.writeTarEntries(tarWriter, files)
+
+	// the final flushes (tar padding, gzip trailer) might fail as well, e.g. on a full disk
+	return firstError(err, tarWriter.Close(), gzipWriter.Close(), archive.Close())
+}
+
+func (this *Packager) writeTarEntries(tarWriter *tar.Writer, files []string) error {
+	for _, file := range files {
+		stagedPath := filepath.Join(this.StagingDir, file)
+		info, err := os.Stat(stagedPath)
+
+		if err != nil {
+			return err
+		}
+
+		header, err := tar.FileInfoHeader(info, "")
+
+		if err != nil {
+			return err
+		}
+
+		header.Name = fmt.Sprintf("%s/%s", this.baseName(), file)
+
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if err := copyFileTo(tarWriter, stagedPath); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (this *Packager) writeZip(files []string, path string) error {
+	archive, err := os.Create(path)
+
+	if err != nil {
+		return err
+	}
+
+	zipWriter := zip.NewWriter(archive)
+	err = this.writeZipEntries(zipWriter, files)
+
+	// the central directory is written on close
+	return firstError(err, zipWriter.Close(), archive.Close())
+}
+
+func (this *Packager) writeZipEntries(zipWriter *zip.Writer, files []string) error {
+	for _, file := range files {
+		stagedPath := filepath.Join(this.StagingDir, file)
+		info, err := os.Stat(stagedPath)
+
+		if err != nil {
+			return err
+		}
+
+		header, err := zip.FileInfoHeader(info)
+
+		if err != nil {
+			return err
+		}
+
+		header.Name = fmt.Sprintf("%s/%s", this.baseName(), file)
+		header.Method = zip.Deflate
+
+		writer, err := zipWriter.CreateHeader(header)
+
+		if err != nil {
+			return err
+		}
+
+		if err := copyFileTo(writer, stagedPath); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func firstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func copyFileTo(writer io.Writer, path string) error {
+	file, err := os.Open(path)
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	_, err = io.Copy(writer, file)
+	return err
+}
+
+func (this *Packager) Package() error {
+	for _, format := range this.Formats {
+		if format != "tar.gz" && format != "zip" {
+			return fmt.Errorf("Unsupported package format `%s`, expected `tar.gz` or `zip`!", format)
+		}
+	}
+
+	if err := os.MkdirAll(this.OutputDir, os.ModePerm); err != nil {
+		return err
+	}
+
+	files, err := this.collectFiles()
+
+	if err != nil {
+		return err
+	}
+
+	if len(files) == 0 {
+		return fmt.Errorf("Nothing to package, no install rules produced any files!")
+	}
+
+	if this.Strip {
+		if err := this.stripBinaries(files); err != nil {
+			return err
+		}
+	}
+
+	manifestPath, err := this.writeManifest(files)
+
+	if err != nil {
+		return err
+	}
+
+	files = append(files, PACKAGE_MANIFEST)
+	fmt.Printf("[package] Wrote manifest %s\n", manifestPath)
+
+	for _, format := range this.Formats {
+		path := filepath.Join(this.OutputDir, fmt.Sprintf("%s.%s", this.baseName(), format))
+
+		if format == "zip" {
+			err = this.writeZip(files, path)
+		} else {
+			err = this.writeTarGz(files, path)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("[package] Wrote %s\n", path)
+	}
+
+	return nil
+}
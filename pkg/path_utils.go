@@ -4,6 +4,10 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
+
+	"github.com/yargevad/filepathx"
+	"golang.org/x/exp/slices"
 )
 
 func RemovePath(path string) {
@@ -12,6 +16,32 @@ func RemovePath(path string) {
 	}
 }
 
+func isDirectory(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+func globPaths(paths []string) ([]string, error) {
+	globbed := []string{}
+
+	for _, path := range paths {
+		if !strings.Contains(path, "*") {
+			globbed = append(globbed, path)
+			continue
+		}
+
+		matches, err := filepathx.Glob(path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		globbed = append(globbed, matches...)
+	}
+
+	return globbed, nil
+}
+
 func collectModTimes(path string) ([]int64, error) {
 	modTimes := []int64{}
 
@@ -54,3 +84,20 @@ func collectModTimesMultiple(paths []string) ([]int64, error) {
 
 	return collected, nil
 }
+
+// Collects the header files below the given paths, non-existing paths are ignored.
+func collectHeaders(paths []string) []string {
+	headers := []string{}
+
+	for _, path := range paths {
+		filepath.Walk(path, func(name string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() && slices.Contains(headerExtensions, filepath.Ext(name)) {
+				headers = append(headers, name)
+			}
+
+			return nil
+		})
+	}
+
+	return headers
+}
@@ -0,0 +1,263 @@
+package gmakec
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+var pkgConfigVariablePattern = regexp.MustCompile(`\$\{([A-Za-z0-9_.]+)\}`)
+
+type pkgConfigRequirement struct {
+	Name       string
+	Constraint string
+}
+
+type pkgConfigModule struct {
+	Name     string
+	Path     string
+	Version  string
+	Cflags   []string
+	Libs     []string
+	Requires []pkgConfigRequirement
+	// only contribute compile flags, as they are linked by the requiring library itself
+	RequiresPrivate []pkgConfigRequirement
+}
+
+// Splits a flag string like the shell would, honoring quotes and backslashes.
+func splitFlags(text string) []string {
+	flags := []string{}
+	current := strings.Builder{}
+	quote := rune(0)
+	escaped := false
+	inFlag := false
+
+	for _, r := range text {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inFlag = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inFlag = true
+		case r == ' ' || r == '\t':
+			if inFlag {
+				flags = append(flags, current.String())
+				current.Reset()
+				inFlag = false
+			}
+		default:
+			current.WriteRune(r)
+			inFlag = true
+		}
+	}
+
+	if inFlag {
+		flags = append(flags, current.String())
+	}
+
+	return flags
+}
+
+// Parses `Requires` fields like `glib-2.0 >= 2.50, gobject-2.0`.
+func parsePkgConfigRequirements(text string) []pkgConfigRequirement {
+	requirements := []pkgConfigRequirement{}
+	tokens := strings.Fields(strings.ReplaceAll(text, ",", " "))
+
+	for index := 0; index < len(tokens); index++ {
+		requirement := pkgConfigRequirement{Name: tokens[index]}
+
+		if index+2 < len(tokens) && slices.Contains(versionOperators, tokens[index+1]) {
+			requirement.Constraint = fmt.Sprintf("%s %s", tokens[index+1], tokens[index+2])
+			index += 2
+		}
+
+		requirements = append(requirements, requirement)
+	}
+
+	return requirements
+}
+
+func parsePkgConfigFile(path string) (*pkgConfigModule, error) {
+	file, err := os.Open(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	module := &pkgConfigModule{
+		Name: strings.TrimSuffix(filepath.Base(path), ".pc"),
+		Path: path,
+	}
+
+	variables := map[string]string{
+		"pcfiledir": filepath.Dir(path),
+	}
+
+	expand := func(value string) string {
+		// variables may reference other variables, which are already expanded
+		return pkgConfigVariablePattern.ReplaceAllStringFunc(value, func(match string) string {
+			return variables[pkgConfigVariablePattern.FindStringSubmatch(match)[1]]
+		})
+	}
+
+	fileScanner := bufio.NewScanner(file)
+	fileScanner.Split(bufio.ScanLines)
+
+	for fileScanner.Scan() {
+		line := fileScanner.Text()
+
+		if commentIndex := strings.Index(line, "#"); commentIndex >= 0 {
+			line = line[:commentIndex]
+		}
+
+		line = strings.TrimSpace(line)
+		separatorIndex := strings.IndexAny(line, "=:")
+
+		if separatorIndex <= 0 {
+			continue
+		}
+
+		key := strings.TrimSpace(line[:separatorIndex])
+		value := expand(strings.TrimSpace(line[separatorIndex+1:]))
+
+		if line[separatorIndex] == '=' {
+			variables[key] = value
+			continue
+		}
+
+		switch key {
+		case "Version":
+			module.Version = value
+		case "Cflags":
+			module.Cflags = splitFlags(value)
+		case "Libs":
+			module.Libs = splitFlags(value)
+		case "Requires":
+			module.Requires = parsePkgConfigRequirements(value)
+		case "Requires.private":
+			module.RequiresPrivate = parsePkgConfigRequirements(value)
+		}
+	}
+
+	if err := fileScanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return module, nil
+}
+
+func findPkgConfigFile(name string, searchPaths []string) string {
+	for _, searchPath := range searchPaths {
+		path := filepath.Join(searchPath, fmt.Sprintf("%s.pc", name))
+
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+
+	return ""
+}
+
+func isSystemPkgConfigFlag(flag string) bool {
+	for _, systemDir := range []string{"/usr/include", "/usr/lib", "/lib"} {
+		if flag == fmt.Sprintf("-I%s", systemDir) || flag == fmt.Sprintf("-L%s", systemDir) {
+			return true
+		}
+	}
+
+	return slices.Contains(systemLibraryDirs(), strings.TrimPrefix(flag, "-L"))
+}
+
+func appendUniqueFlags(flags []string, newFlags ...string) []string {
+	for _, flag := range newFlags {
+		if !isSystemPkgConfigFlag(flag) && !slices.Contains(flags, flag) {
+			flags = append(flags, flag)
+		}
+	}
+
+	return flags
+}
+
+type pkgConfigResolver struct {
+	SearchPaths []string
+	Visited     []string
+	Private     bool
+	Cflags      []string
+	Libs        []string
+}
+
+// Resolves a module and all of its requirements recursively, collecting their flags.
+func (this *pkgConfigResolver) resolve(name string, constraint string) (*pkgConfigModule, error) {
+	path := findPkgConfigFile(name, this.SearchPaths)
+
+	if len(path) == 0 {
+		return nil, fmt.Errorf("pkg-config module `%s` not found in %v", name, this.SearchPaths)
+	}
+
+	module, err := parsePkgConfigFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(constraint) > 0 {
+		ok, err := versionSatisfies(module.Version, constraint)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			return nil, fmt.Errorf(
+				"pkg-config module `%s` (%s) has version %s, which does not satisfy `%s`",
+				name, path, module.Version, constraint,
+			)
+		}
+	}
+
+	if slices.Contains(this.Visited, name) {
+		return module, nil
+	}
+
+	this.Visited = append(this.Visited, name)
+	this.Cflags = appendUniqueFlags(this.Cflags, module.Cflags...)
+
+	if !this.Private {
+		this.Libs = appendUniqueFlags(this.Libs, module.Libs...)
+	}
+
+	for _, requirement := range module.Requires {
+		if _, err := this.resolve(requirement.Name, requirement.Constraint); err != nil {
+			return nil, err
+		}
+	}
+
+	private := this.Private
+	this.Private = true
+
+	for _, requirement := range module.RequiresPrivate {
+		if _, err := this.resolve(requirement.Name, requirement.Constraint); err != nil {
+			return nil, err
+		}
+	}
+
+	this.Private = private
+	return module, nil
+}
@@ -0,0 +1,135 @@
+package gmakec
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+var precompiledHeaderTypes = map[string]string{
+	LANGUAGE_C:   "c-header",
+	LANGUAGE_CXX: "c++-header",
+}
+
+type PrecompiledHeader struct {
+	Language string
+	Compiler *CompilerDefinition
+	Flags    []string
+	Output   string
+}
+
+// Languages of the target which get a precompiled header. Targets with a single
+// compiler compile all sources at once, so they can only use one.
+func (this *Target) precompiledHeaderLanguages() []string {
+	languages := []string{}
+
+	if len(this.Definition.PrecompiledHeader) == 0 {
+		return languages
+	}
+
+	for _, source := range this.Sources {
+		language, err := sourceLanguage(source)
+
+		if err != nil {
+			continue
+		}
+
+		if _, ok := precompiledHeaderTypes[language]; ok && !slices.Contains(languages, language) {
+			languages = append(languages, language)
+		}
+	}
+
+	if this.isMixed() {
+		// not every compiler family supports precompiled headers
+		return slices.DeleteFunc(languages, func(language string) bool {
+			return len(this.Languages[language].Object.PrecompiledHeaderExtension) == 0
+		})
+	}
+
+	if len(this.Definition.Compiler.Object.PrecompiledHeaderExtension) == 0 {
+		return []string{}
+	}
+
+	if slices.Contains(languages, LANGUAGE_CXX) || strings.HasSuffix(this.Definition.Compiler.Object.Name, "++") {
+		return []string{LANGUAGE_CXX}
+	}
+
+	return []string{LANGUAGE_C}
+}
+
+func (this *Target) precompiledHeader(language string) *PrecompiledHeader {
+	if !slices.Contains(this.precompiledHeaderLanguages(), language) {
+		return nil
+	}
+
+	compilerDef := &this.Definition.Compiler
+	flags := append([]string{}, compilerDef.Flags...)
+
+	if this.isMixed() {
+		compilerDef = this.Languages[language]
+		findFlags, _ := compilerDef.findFlags()
+		flags = append(append([]string{}, compilerDef.Flags...), findFlags...)
+	} else {
+		flags = append(flags, this.Flags...)
+	}
+
+	flags = append(flags, this.Defines...)
+	flags = append(flags, this.Includes...)
+
+	// the header has to be compiled with the flags of the sources including it
+	flagSet := sha1.Sum([]byte(strings.Join(append([]string{compilerDef.Object.Path}, flags...), " ")))
+	flagSetDir := fmt.Sprintf("%s-%x", language, flagSet[:4])
+
+	output := filepath.Join(
+		filepath.Dir(this.Definition.Output),
+		"pch",
+		filepath.Base(this.Definition.Output),
+		flagSetDir,
+		fmt.Sprintf("%s%s", filepath.Base(this.Definition.PrecompiledHeader), compilerDef.Object.PrecompiledHeaderExtension),
+	)
+
+	return &PrecompiledHeader{
+		Language: language,
+		Compiler: compilerDef,
+		Flags:    flags,
+		Output:   output,
+	}
+}
+
+func (this *PrecompiledHeader) depfile() string {
+	return fmt.Sprintf("%s.d", this.Output)
+}
+
+func (this *PrecompiledHeader) includeFlags() []string {
+	return this.Compiler.Object.precompiledHeaderFlags(this.Output)
+}
+
+func (this *PrecompiledHeader) buildStep(header string) BuildStep {
+	command := append(this.Compiler.launcher(), this.Compiler.Object.command()...)
+	command = append(command, this.Flags...)
+	command = append(command, this.Compiler.Object.LanguageFlag, precompiledHeaderTypes[this.Language], header)
+	command = append(command, this.Compiler.Object.depfileFlags(this.depfile())...)
+	command = append(command, this.Compiler.Object.outputFlags(this.Output)...)
+
+	// the depfile lists everything the header includes, once it got compiled
+	inputs := append([]string{header}, depfileDependencies(this.depfile())...)
+
+	return BuildStep{
+		Command: command,
+		Inputs:  inputs,
+		Outputs: []string{this.Output},
+	}
+}
+
+func (this *Target) precompiledHeaderSteps() []BuildStep {
+	steps := []BuildStep{}
+
+	for _, language := range this.precompiledHeaderLanguages() {
+		steps = append(steps, this.precompiledHeader(language).buildStep(this.Definition.PrecompiledHeader))
+	}
+
+	return steps
+}
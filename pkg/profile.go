@@ -0,0 +1,198 @@
+package gmakec
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+const PROFILE_FILE string = "profile"
+
+var optimizationLevels = []string{"0", "1", "2", "3", "s"}
+
+// A build type contributing flags, defines and an output directory suffix to all targets.
+type Profile struct {
+	Name         string   `yaml:"name"`
+	Optimization string   `yaml:"optimization"`
+	DebugInfo    bool     `yaml:"debug_info"`
+	Flags        []string `yaml:"flags"`
+	Defines      []string `yaml:"defines"`
+	OutputSuffix string   `yaml:"output_suffix"`
+}
+
+var builtinProfiles = []Profile{
+	{Name: "debug", Optimization: "0", DebugInfo: true},
+	{Name: "release", Optimization: "3", Defines: []string{"NDEBUG"}},
+	{Name: "relwithdebinfo", Optimization: "2", DebugInfo: true, Defines: []string{"NDEBUG"}},
+	{Name: "minsizerel", Optimization: "s", Defines: []string{"NDEBUG"}},
+}
+
+// Selected from the command line or by the last configure run, applies to imported projects as well.
+var profileName string
+
+func SetProfile(name string) {
+	profileName = name
+}
+
+// Loads the profile the project was configured with, unless one is selected already.
+func LoadProfile(configureDir string) {
+	if len(profileName) > 0 {
+		return
+	}
+
+	content, err := os.ReadFile(filepath.Join(configureDir, PROFILE_FILE))
+
+	if err == nil {
+		profileName = strings.TrimSpace(string(content))
+	}
+}
+
+func saveProfile(configureDir string) error {
+	path := filepath.Join(configureDir, PROFILE_FILE)
+
+	if len(profileName) == 0 {
+		RemovePath(path)
+		return nil
+	}
+
+	return os.WriteFile(path, []byte(fmt.Sprintf("%s\n", profileName)), 0644)
+}
+
+func findProfile(profiles []Profile) (*Profile, error) {
+	index := slices.IndexFunc(profiles, func(profile Profile) bool {
+		return profile.Name == profileName
+	})
+
+	if index < 0 {
+		return nil, nil
+	}
+
+	profile := profiles[index]
+
+	if len(profile.Optimization) > 0 && !slices.Contains(optimizationLevels, profile.Optimization) {
+		return nil, fmt.Errorf("Profile `%s` has unsupported optimization `%s`, supported are %v!", profile.Name, profile.Optimization, optimizationLevels)
+	}
+
+	if len(profile.OutputSuffix) == 0 {
+		profile.OutputSuffix = profile.Name
+	}
+
+	return &profile, nil
+}
+
+// Resolves the profile once all definitions are loaded, so one defined by an import only can be
+// selected as well. Profiles of the root definition are used by all imports, otherwise a definition
+// uses its own one, the one of another definition and finally the built-in one.
+func (this *DefinitionContext) SanitizeProfiles(definitionContexts []*DefinitionContext) error {
+	if len(profileName) == 0 {
+		return nil
+	}
+
+	var sharedProfile *Profile
+	var err error
+
+	for _, definitionContext := range append([]*DefinitionContext{this}, definitionContexts...) {
+		if sharedProfile, err = findProfile(definitionContext.Definition.Profiles); err != nil || sharedProfile != nil {
+			break
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if sharedProfile == nil {
+		if sharedProfile, err = findProfile(builtinProfiles); err != nil {
+			return err
+		}
+	}
+
+	if sharedProfile == nil {
+		return fmt.Errorf("Unknown profile `%s`!", profileName)
+	}
+
+	rootProfile, err := findProfile(this.Definition.Profiles)
+
+	if err != nil {
+		return err
+	}
+
+	for _, definitionContext := range definitionContexts {
+		definition := definitionContext.Definition
+
+		if definition.Profile = rootProfile; definition.Profile == nil {
+			if definition.Profile, err = findProfile(definition.Profiles); err != nil {
+				return err
+			}
+		}
+
+		if definition.Profile == nil {
+			definition.Profile = sharedProfile
+		}
+
+		for index := range definition.Targets {
+			if !definition.Targets[index].isCommand() {
+				definition.Targets[index].Output = definition.Profile.outputPath(definition.Targets[index].Output)
+			}
+		}
+	}
+
+	return nil
+}
+
+// E.g. build/app becomes build/release/app.
+func (this *Profile) outputPath(output string) string {
+	if this == nil {
+		return output
+	}
+
+	return filepath.Join(filepath.Dir(output), this.OutputSuffix, filepath.Base(output))
+}
+
+func (this *Profile) compilerFlags(compiler *Compiler) []string {
+	flags := []string{}
+
+	if this == nil {
+		return flags
+	}
+
+	flags = append(flags, compiler.OptimizationFlags[this.Optimization]...)
+
+	if this.DebugInfo && len(compiler.DebugInfoFlag) > 0 {
+		flags = append(flags, compiler.DebugInfoFlag)
+	}
+
+	return append(flags, this.Flags...)
+}
+
+func (this *Profile) defines(compiler *Compiler) []string {
+	defines := []string{}
+
+	if this == nil {
+		return defines
+	}
+
+	for _, define := range this.Defines {
+		defines = append(defines, compiler.DefineFlag, define)
+	}
+
+	return defines
+}
+
+func (this *TargetDefinition) applyProfile(profile *Profile, languages map[string]*CompilerDefinition) {
+	if len(languages) == 0 {
+		this.Compiler.Flags = append(this.Compiler.Flags, profile.compilerFlags(this.Compiler.Object)...)
+		return
+	}
+
+	for language, compilerDef := range languages {
+		if language == LANGUAGE_ASM && compilerDef == languages[LANGUAGE_C] {
+			continue
+		}
+
+		compilerDef.Flags = append(slices.Clone(compilerDef.Flags), profile.compilerFlags(compilerDef.Object)...)
+	}
+}
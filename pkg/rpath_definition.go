@@ -0,0 +1,54 @@
+package gmakec
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+type RPathDefinition struct {
+	// Disables the build tree RPATH pointing to gmakec built shared libraries.
+	SkipBuild bool `yaml:"skip_build"`
+	// RPATH entries replacing the build tree RPATH on install. Empty strips it.
+	Install []string `yaml:"install"`
+}
+
+func rpathOrigin() string {
+	if targetPlatform() == "darwin" {
+		return "@loader_path"
+	}
+
+	return "$ORIGIN"
+}
+
+func isSharedLibrary(path string) bool {
+	extension := filepath.Ext(path)
+	return extension == ".so" || extension == ".dylib" || strings.Contains(filepath.Base(path), ".so.")
+}
+
+func buildTreeRPath(outputPath string, libraryPath string) (string, error) {
+	outputDir, err := filepath.Abs(filepath.Dir(outputPath))
+
+	if err != nil {
+		return "", err
+	}
+
+	libraryDir, err := filepath.Abs(filepath.Dir(libraryPath))
+
+	if err != nil {
+		return "", err
+	}
+
+	relativeDir, err := filepath.Rel(outputDir, libraryDir)
+
+	if err != nil {
+		return "", err
+	}
+
+	if relativeDir == "." {
+		return rpathOrigin(), nil
+	}
+
+	// filepath.Join would clean away the leading `..` elements
+	return fmt.Sprintf("%s/%s", rpathOrigin(), filepath.ToSlash(relativeDir)), nil
+}
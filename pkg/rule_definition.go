@@ -0,0 +1,116 @@
+package gmakec
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+type RuleDefinition struct {
+	Name      string `yaml:"name"`
+	Extension string `yaml:"extension"`
+	// extensions of the generated files, e.g. [.c, .h]
+	Outputs []string `yaml:"outputs"`
+	Shell   string   `yaml:"shell"`
+	// supports <input>, <output> (first output) and <output:EXT>, e.g. <output:.h>
+	Command string `yaml:"command"`
+}
+
+type GeneratedSource struct {
+	Rule    *RuleDefinition
+	Input   string
+	Outputs []string
+}
+
+func findRule(source string, rules *[]RuleDefinition) *RuleDefinition {
+	for index := range *rules {
+		if (*rules)[index].Extension == filepath.Ext(source) {
+			return &(*rules)[index]
+		}
+	}
+
+	return nil
+}
+
+func (this *RuleDefinition) generatedSource(input string, outputDir string) *GeneratedSource {
+	relativePath := filepath.Clean(input)
+
+	if filepath.IsAbs(relativePath) || strings.HasPrefix(relativePath, "..") {
+		relativePath = filepath.Base(relativePath)
+	}
+
+	generated := &GeneratedSource{
+		Rule:  this,
+		Input: input,
+	}
+
+	for _, extension := range this.Outputs {
+		output := filepath.Join(outputDir, strings.TrimSuffix(relativePath, this.Extension)+extension)
+		generated.Outputs = append(generated.Outputs, output)
+	}
+
+	return generated
+}
+
+func (this *GeneratedSource) command() string {
+	command := strings.ReplaceAll(this.Rule.Command, "<input>", this.Input)
+	command = strings.ReplaceAll(command, "<output>", this.Outputs[0])
+
+	for index, extension := range this.Rule.Outputs {
+		command = strings.ReplaceAll(command, fmt.Sprintf("<output:%s>", extension), this.Outputs[index])
+	}
+
+	return command
+}
+
+func (this *GeneratedSource) needsRegeneration() (bool, error) {
+	inputModTimes, err := collectModTimes(this.Input)
+
+	if err != nil {
+		return false, err
+	}
+
+	for _, output := range this.Outputs {
+		outputModTimes, err := collectModTimes(output)
+
+		if err != nil {
+			return false, err
+		}
+
+		if len(outputModTimes) == 0 {
+			return true, nil
+		}
+
+		if len(inputModTimes) > 0 && slices.Max(inputModTimes) > slices.Min(outputModTimes) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (this *GeneratedSource) generate(workingDir string) error {
+	regenerate, err := this.needsRegeneration()
+
+	if err != nil || !regenerate {
+		return err
+	}
+
+	for _, output := range this.Outputs {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(workingDir, output)), os.ModePerm); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("[build] Generating %v from %s (rule: %s)\n", this.Outputs, this.Input, this.Rule.Name)
+
+	action := &ActionDefinition{
+		Name:  this.Rule.Name,
+		Shell: this.Rule.Shell,
+	}
+
+	return executeCommand(action.shellCommand(this.command()), workingDir)
+}
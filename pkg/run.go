@@ -0,0 +1,127 @@
+package gmakec
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// Collects the target and all of its dependencies recursively, across all definitions.
+func TargetDependencies(targetName string, definitionContexts *[]*DefinitionContext) ([]string, error) {
+	names := []string{}
+	pending := []string{targetName}
+
+	for len(pending) > 0 {
+		name := pending[0]
+		pending = pending[1:]
+
+		if slices.Contains(names, name) {
+			continue
+		}
+
+		refContext, refTarget := findRefTarget(name, definitionContexts)
+
+		if refContext == nil || refTarget == nil {
+			return nil, fmt.Errorf("Could not find target of name `%s`!", name)
+		}
+
+		names = append(names, name)
+		pending = append(pending, refTarget.Dependencies...)
+	}
+
+	return names, nil
+}
+
+func libraryPathVariable() string {
+	switch runtime.GOOS {
+	case "darwin":
+		return "DYLD_LIBRARY_PATH"
+	case "windows":
+		return "PATH"
+	}
+
+	return "LD_LIBRARY_PATH"
+}
+
+// Runs the output of a target, finding gmakec built shared libraries it depends on.
+// Returns the exit code of the executed program.
+func RunTarget(targetName string, args []string, definitionContexts *[]*DefinitionContext) (int, error) {
+	refContext, refTarget := findRefTarget(targetName, definitionContexts)
+
+	if refContext == nil || refTarget == nil {
+		return 1, fmt.Errorf("Could not find target of name `%s`!", targetName)
+	}
+
+	if refTarget.isCommand() {
+		return 1, fmt.Errorf("Target `%s` is a command target and cannot be run!", targetName)
+	}
+
+	executable, err := filepath.Abs(filepath.Join(refContext.DefinitionPath, refTarget.Output))
+
+	if err != nil {
+		return 1, err
+	}
+
+	dependencies, err := TargetDependencies(targetName, definitionContexts)
+
+	if err != nil {
+		return 1, err
+	}
+
+	libraryDirs := []string{}
+
+	for _, dependency := range dependencies {
+		depContext, depTarget := findRefTarget(dependency, definitionContexts)
+
+		if depTarget.isCommand() || !isSharedLibrary(depTarget.Output) {
+			continue
+		}
+
+		libraryDir, err := filepath.Abs(filepath.Dir(filepath.Join(depContext.DefinitionPath, depTarget.Output)))
+
+		if err != nil {
+			return 1, err
+		}
+
+		if !slices.Contains(libraryDirs, libraryDir) {
+			libraryDirs = append(libraryDirs, libraryDir)
+		}
+	}
+
+	command := exec.Command(executable, args...)
+	command.Stdin = os.Stdin
+	command.Stdout = os.Stdout
+	command.Stderr = os.Stderr
+	command.Env = os.Environ()
+
+	if len(libraryDirs) > 0 {
+		variable := libraryPathVariable()
+
+		if existing := os.Getenv(variable); len(existing) > 0 {
+			libraryDirs = append(libraryDirs, existing)
+		}
+
+		command.Env = append(command.Env, fmt.Sprintf(
+			"%s=%s", variable, strings.Join(libraryDirs, string(os.PathListSeparator)),
+		))
+	}
+
+	err = command.Run()
+	var exitError *exec.ExitError
+
+	if errors.As(err, &exitError) {
+		return exitError.ExitCode(), nil
+	}
+
+	if err != nil {
+		return 1, err
+	}
+
+	return 0, nil
+}
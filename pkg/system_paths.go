@@ -0,0 +1,90 @@
+package gmakec
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Multiarch directories like /usr/lib/x86_64-linux-gnu on Debian based systems.
+func multiarchDirs(dir string) []string {
+	matches, err := filepath.Glob(filepath.Join(dir, "*-linux-*"))
+
+	if err != nil {
+		return nil
+	}
+
+	return matches
+}
+
+func existingDirs(dirs []string) []string {
+	existing := []string{}
+
+	for _, dir := range dirs {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			existing = append(existing, dir)
+		}
+	}
+
+	return existing
+}
+
+func systemLibraryDirs() []string {
+	dirs := []string{}
+
+	if toolchain != nil {
+		dirs = append(dirs, toolchain.LibraryPaths...)
+	}
+
+	for _, prefix := range sysrootPaths("/usr/local/lib", "/usr/lib", "/lib") {
+		dirs = append(dirs, multiarchDirs(prefix)...)
+		dirs = append(dirs, prefix)
+		dirs = append(dirs, prefix+"64")
+	}
+
+	return existingDirs(dirs)
+}
+
+func systemIncludeDirs() []string {
+	dirs := []string{}
+
+	if toolchain != nil {
+		dirs = append(dirs, toolchain.IncludePaths...)
+	}
+
+	for _, prefix := range sysrootPaths("/usr/local/include", "/usr/include") {
+		dirs = append(dirs, prefix)
+		dirs = append(dirs, multiarchDirs(prefix)...)
+	}
+
+	return existingDirs(dirs)
+}
+
+func pkgConfigDirs() []string {
+	dirs := []string{}
+
+	for _, envName := range []string{"PKG_CONFIG_PATH", "PKG_CONFIG_LIBDIR"} {
+		for _, dir := range filepath.SplitList(os.Getenv(envName)) {
+			if len(dir) > 0 {
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+
+	// PKG_CONFIG_LIBDIR replaces the default search path
+	if len(os.Getenv("PKG_CONFIG_LIBDIR")) > 0 {
+		return dirs
+	}
+
+	for _, libraryDir := range systemLibraryDirs() {
+		dirs = append(dirs, filepath.Join(libraryDir, "pkgconfig"))
+	}
+
+	dirs = append(dirs, sysrootPaths("/usr/local/share/pkgconfig", "/usr/share/pkgconfig")...)
+	return existingDirs(dirs)
+}
+
+func expandUserPath(path string) string {
+	// TODO: this is not portable at all, POSIX only. But it works for now.
+	return os.ExpandEnv(strings.ReplaceAll(path, "~", "${HOME}"))
+}
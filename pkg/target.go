@@ -2,6 +2,8 @@ package gmakec
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"golang.org/x/exp/slices"
@@ -10,84 +12,358 @@ import (
 type Target struct {
 	Definition *TargetDefinition
 	Index      int
+	Flags      []string
 	Defines    []string
 	Includes   []string
 	Links      []string
+	Libraries  []string
+	RPaths     []string
 	Sources    []string
+	Generated  []GeneratedSource
+	Unity      []UnitySource
+	Languages  map[string]*CompilerDefinition
 }
 
-func (this *Target) needsRebuild() (bool, error) {
-	outputModTimes, err := collectModTimes(this.Definition.Output)
+// Replaces sources matching a rule with the files generated from them.
+func (this *Target) applyRules(rules *[]RuleDefinition) {
+	sources := this.Sources
+	this.Sources = []string{}
+	outputDir := filepath.Join(filepath.Dir(this.Definition.Output), "generated")
+
+	for _, source := range sources {
+		rule := findRule(source, rules)
+
+		if rule == nil {
+			this.Sources = append(this.Sources, source)
+			continue
+		}
+
+		generated := rule.generatedSource(source, outputDir)
+		this.Generated = append(this.Generated, *generated)
+
+		for _, output := range generated.Outputs {
+			if !slices.Contains(headerExtensions, filepath.Ext(output)) {
+				this.Sources = append(this.Sources, output)
+				continue
+			}
+
+			includeDir := filepath.Dir(output)
 
-	if err != nil {
-		return false, err
+			if !slices.Contains(this.Includes, includeDir) {
+				this.Includes = append(this.Includes, this.Definition.Compiler.Object.IncludeSearchFlag)
+				this.Includes = append(this.Includes, includeDir)
+			}
+		}
 	}
+}
 
-	if len(outputModTimes) == 0 {
-		return true, nil
+func (this *Target) generateSources(workingDir string) error {
+	for index := range this.Generated {
+		if err := this.Generated[index].generate(workingDir); err != nil {
+			return err
+		}
 	}
 
-	outputMax := slices.Max(outputModTimes)
-	includeModTimes, err := collectModTimesMultiple(this.Includes)
+	return nil
+}
+
+func (this *Target) isMixed() bool {
+	return len(this.Languages) > 0
+}
+
+// Compiled per source and linked separately, e.g. for link launchers, which the combined command cannot have.
+func (this *Target) isSplit() bool {
+	return this.isMixed() || (len(this.Definition.Compiler.linkLauncher()) > 0 && !this.Definition.compilesOnly())
+}
+
+// Mixed language targets link with the C++ driver as soon as there is any C++ source.
+func (this *Target) selectLinkDriver() error {
+	languages := []string{}
+
+	for _, source := range this.Sources {
+		language, err := sourceLanguage(source)
+
+		if err != nil {
+			return err
+		}
 
-	if err != nil {
-		return false, err
+		if _, ok := this.Languages[language]; !ok {
+			return fmt.Errorf("Target `%s` has %s source `%s`, but no %s compiler!", this.Definition.Name, language, source, language)
+		}
+
+		languages = append(languages, language)
 	}
 
-	linkModTimes, err := collectModTimesMultiple(this.Links)
+	driver := primaryLanguageCompiler(this.Languages)
 
-	if err != nil {
-		return false, err
+	if slices.Contains(languages, LANGUAGE_CXX) {
+		driver = this.Languages[LANGUAGE_CXX]
 	}
 
-	sourceModTimes, err := collectModTimesMultiple(this.Sources)
+	this.Definition.Compiler = *driver
+	this.Libraries = []string{}
 
-	if err != nil {
-		return false, err
+	for _, compilerDef := range this.Languages {
+		_, libraries := compilerDef.findFlags()
+		this.Libraries = appendUniqueFlags(this.Libraries, libraries...)
 	}
 
-	if len(includeModTimes) > 0 && slices.Max(includeModTimes) > outputMax {
-		return true, nil
+	return nil
+}
+
+func (this *Target) inputs() []string {
+	inputs := append([]string{}, this.Includes...)
+	inputs = append(inputs, this.Links...)
+
+	for _, source := range this.Sources {
+		inputs = append(inputs, this.includedSources(source)...)
 	}
 
-	if len(linkModTimes) > 0 && slices.Max(linkModTimes) > outputMax {
-		return true, nil
+	for _, generated := range this.Generated {
+		inputs = append(inputs, generated.Input)
 	}
 
-	if len(sourceModTimes) > 0 && slices.Max(sourceModTimes) > outputMax {
-		return true, nil
+	for _, language := range this.precompiledHeaderLanguages() {
+		inputs = append(inputs, this.precompiledHeader(language).Output)
 	}
 
-	return false, nil
+	return inputs
 }
 
-func (this *Target) buildCommand() (string, error) {
-	rebuild, err := this.needsRebuild()
+func (this *Target) objectPath(source string) string {
+	relativePath := filepath.Clean(source)
+
+	if filepath.IsAbs(relativePath) || strings.HasPrefix(relativePath, "..") {
+		relativePath = filepath.Base(relativePath)
+	}
+
+	// generated and unity sources already live in the output directory
+	if outputRelativePath, err := filepath.Rel(filepath.Dir(this.Definition.Output), source); err == nil && !strings.HasPrefix(outputRelativePath, "..") {
+		relativePath = outputRelativePath
+	}
+
+	objectDir := filepath.Join(filepath.Dir(this.Definition.Output), "objects", filepath.Base(this.Definition.Output))
+	return filepath.Join(objectDir, fmt.Sprintf("%s%s", relativePath, this.sourceCompiler(source).Object.ObjectExtension))
+}
 
-	if err != nil {
-		return "", err
+func (this *Target) sourceCompiler(source string) *CompilerDefinition {
+	if language, err := sourceLanguage(source); err == nil && this.isMixed() {
+		return this.Languages[language]
 	}
 
-	command := []string{
-		fmt.Sprintf("%d", this.Index),
+	return &this.Definition.Compiler
+}
+
+func (this *Target) compileStep(source string) BuildStep {
+	language, _ := sourceLanguage(source)
+	compilerDef := this.sourceCompiler(source)
+	flags, _ := compilerDef.findFlags()
+	object := this.objectPath(source)
+
+	command := append(compilerDef.launcher(), compilerDef.Object.command()...)
+	command = append(command, compilerDef.Flags...)
+	command = append(command, flags...)
+	command = append(command, this.Defines...)
+	command = append(command, this.Includes...)
+
+	precompiledHeader := this.precompiledHeader(language)
+
+	if precompiledHeader != nil {
+		command = append(command, precompiledHeader.includeFlags()...)
 	}
 
-	if rebuild {
-		command = append(command, "build")
+	depfile := fmt.Sprintf("%s.d", object)
+	command = append(command, compilerDef.Object.depfileFlags(depfile)...)
+	command = append(command, compilerDef.Object.CompileOnlyFlag, source)
+	command = append(command, compilerDef.Object.objectOutputFlags(object)...)
+
+	sources := this.includedSources(source)
+	inputs := append([]string{}, sources...)
+
+	// the depfile lists the headers the source includes, once it got compiled. Without one
+	// all headers of the include dirs are inputs, the dirs might contain the sources as well.
+	if _, err := os.Stat(depfile); err == nil && len(compilerDef.Object.DepfileFlags) > 0 {
+		inputs = append(inputs, depfileDependencies(depfile)...)
 	} else {
-		command = append(command, "skip")
+		inputs = append(inputs, collectHeaders(this.Includes)...)
+	}
+
+	for _, generated := range this.Generated {
+		for _, output := range generated.Outputs {
+			if slices.Contains(sources, output) {
+				inputs = append(inputs, generated.Input)
+			}
+		}
+	}
+
+	if precompiledHeader != nil {
+		inputs = append(inputs, precompiledHeader.Output)
+	}
+
+	return BuildStep{
+		Command: command,
+		Inputs:  inputs,
+		Outputs: []string{object},
+	}
+}
+
+func (this *Target) objects() []string {
+	objects := []string{}
+
+	for _, source := range this.Sources {
+		// e.g. objects of other targets are linked as they are
+		if _, err := sourceLanguage(source); err != nil {
+			objects = append(objects, source)
+			continue
+		}
+
+		objects = append(objects, this.objectPath(source))
 	}
 
-	command = append(command, this.Definition.Compiler.Object.Path)
+	return objects
+}
+
+func (this *Target) linkArguments() []string {
+	return this.Definition.Compiler.Object.linkArguments(append(slices.Clone(this.Links), this.Libraries...))
+}
+
+// Shared libraries are linked with the shared flag of the compiler family, unless it is among the flags already.
+func (this *Target) sharedFlags() []string {
+	compiler := this.Definition.Compiler.Object
+	flags := append(slices.Clone(this.Definition.Compiler.Flags), this.Definition.LinkFlags...)
+
+	if !this.Definition.isSharedLibrary() || len(compiler.SharedFlag) == 0 || slices.Contains(flags, compiler.SharedFlag) {
+		return []string{}
+	}
+
+	return []string{compiler.SharedFlag}
+}
+
+func (this *Target) linkCommand(output string, rpaths []string) []string {
+	if !this.isSplit() {
+		return this.compilerCommand(output, rpaths)
+	}
+
+	command := append(this.Definition.Compiler.linkLauncher(), this.Definition.Compiler.Object.command()...)
+
+	// the flags of a single compiler apply to linking as well, as with the combined command
+	if !this.isMixed() {
+		command = append(command, this.Definition.Compiler.Flags...)
+	}
+
+	command = append(command, this.Definition.LinkFlags...)
+	command = append(command, this.sharedFlags()...)
+
+	command = append(command, this.Definition.Compiler.Object.rpathFlags(rpaths)...)
+	command = append(command, this.Definition.Compiler.Object.outputFlags(output)...)
+	command = append(command, this.objects()...)
+	command = append(command, this.linkArguments()...)
+	return command
+}
+
+func (this *Target) buildSteps() []BuildStep {
+	if this.Definition.isCommand() {
+		return []BuildStep{{
+			Inputs:  this.Sources,
+			Outputs: this.Definition.Outputs,
+		}}
+	}
+
+	steps := this.precompiledHeaderSteps()
+
+	if !this.isSplit() {
+		return append(steps, BuildStep{
+			Command: this.compilerCommand(this.Definition.Output, this.RPaths),
+			Inputs:  this.inputs(),
+			Outputs: []string{this.Definition.Output},
+		})
+	}
+
+	for _, source := range this.Sources {
+		if _, err := sourceLanguage(source); err == nil {
+			steps = append(steps, this.compileStep(source))
+		}
+	}
+
+	return append(steps, BuildStep{
+		Command: this.linkCommand(this.Definition.Output, this.RPaths),
+		Inputs:  append(this.objects(), this.Links...),
+		Outputs: []string{this.Definition.Output},
+	})
+}
+
+func (this *Target) needsRebuild() (bool, error) {
+	steps := this.buildSteps()
+
+	for index := range steps {
+		rebuild, err := steps[index].needsRebuild()
+
+		if err != nil || rebuild {
+			return rebuild, err
+		}
+	}
+
+	return false, nil
+}
+
+// Compiles and links all sources at once, which is a compile step for launchers.
+func (this *Target) compilerCommand(output string, rpaths []string) []string {
+	command := append(this.Definition.Compiler.launcher(), this.Definition.Compiler.Object.command()...)
 
 	command = append(command, this.Definition.Compiler.Flags...)
+	command = append(command, this.Flags...)
 	command = append(command, this.Defines...)
 	command = append(command, this.Includes...)
-	command = append(command, this.Links...)
 
-	command = append(command, this.Definition.Compiler.Object.OutputFlag)
-	command = append(command, this.Definition.Output)
+	for _, language := range this.precompiledHeaderLanguages() {
+		command = append(command, this.precompiledHeader(language).includeFlags()...)
+	}
+
+	command = append(command, this.Definition.Compiler.Object.rpathFlags(rpaths)...)
+
+	if this.Definition.compilesOnly() {
+		command = append(command, this.Definition.Compiler.Object.objectOutputFlags(output)...)
+		return append(command, this.Sources...)
+	}
 
+	command = append(command, this.sharedFlags()...)
+	command = append(command, this.Definition.Compiler.Object.outputFlags(output)...)
 	command = append(command, this.Sources...)
-	return strings.Join(command, " "), nil
+
+	// libraries need to come after the sources referencing them
+	return append(command, this.linkArguments()...)
+}
+
+// One line per build step: <target index> <build|skip> <command...>
+func (this *Target) buildCommands() ([]string, error) {
+	lines := []string{}
+	steps := this.buildSteps()
+
+	for index := range steps {
+		rebuild, err := steps[index].needsRebuild()
+
+		if err != nil {
+			return nil, err
+		}
+
+		command := []string{
+			fmt.Sprintf("%d", this.Index),
+		}
+
+		if rebuild {
+			command = append(command, "build")
+		} else {
+			command = append(command, "skip")
+		}
+
+		if this.Definition.isCommand() {
+			command = append(command, TARGET_COMMAND_PLACEHOLDER)
+		} else {
+			command = append(command, steps[index].Command...)
+		}
+
+		lines = append(lines, strings.Join(command, " "))
+	}
+
+	return lines, nil
 }
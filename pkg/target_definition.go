@@ -8,18 +8,97 @@ import (
 	"golang.org/x/exp/slices"
 )
 
+const TARGET_TYPE_COMMAND string = "command"
+
+// Written to the groups file instead of the command itself, which is run from the target definition.
+const TARGET_COMMAND_PLACEHOLDER string = "<command>"
+
 type TargetDefinition struct {
-	Name           string             `yaml:"name"`
-	Platform       string             `yaml:"platform"`
-	Compiler       CompilerDefinition `yaml:"compiler"`
-	ConfigureFiles []ConfigureFile    `yaml:"configure_files"`
-	Defines        []string           `yaml:"defines"`
-	Sources        []SourceDefinition `yaml:"sources"`
-	Includes       []string           `yaml:"includes"`
-	Links          []LinkDefinition   `yaml:"links"`
-	Output         string             `yaml:"output"`
-	Dependencies   []string           `yaml:"dependencies"`
-	Hooks          []HookDefinition   `yaml:"hooks"`
+	Name           string                      `yaml:"name"`
+	Type           string                      `yaml:"type"`
+	Platform       string                      `yaml:"platform"`
+	Compiler       CompilerDefinition          `yaml:"compiler"`
+	ConfigureFiles []ConfigureFile             `yaml:"configure_files"`
+	Defines        []string                    `yaml:"defines"`
+	Sources        []SourceDefinition          `yaml:"sources"`
+	Includes       []string                    `yaml:"includes"`
+	Links          []LinkDefinition            `yaml:"links"`
+	Output         string                      `yaml:"output"`
+	Dependencies   []string                    `yaml:"dependencies"`
+	Hooks          []HookDefinition            `yaml:"hooks"`
+	RPath          RPathDefinition             `yaml:"rpath"`
+	Install        []InstallDefinition         `yaml:"install"`
+	Command        string                      `yaml:"command"`
+	Shell          string                      `yaml:"shell"`
+	Inputs         []string                    `yaml:"inputs"`
+	Outputs        []string                    `yaml:"outputs"`
+	Test           bool                        `yaml:"test"`
+	Languages      LanguageCompilersDefinition `yaml:"languages"`
+	LinkFlags      []string                    `yaml:"link_flags"`
+
+	PrecompiledHeader string `yaml:"precompiled_header"`
+	Unity             bool   `yaml:"unity"`
+	UnityBatchSize    int    `yaml:"unity_batch_size"`
+
+	Checks []CheckDefinition `yaml:"checks"`
+
+	LanguageSettings `yaml:",inline"`
+}
+
+func (this *TargetDefinition) isCommand() bool {
+	return this.Type == TARGET_TYPE_COMMAND
+}
+
+func (this *TargetDefinition) outputPaths() []string {
+	if this.isCommand() {
+		return this.Outputs
+	}
+
+	return []string{this.Output}
+}
+
+// Runs the command of a command target, which generates its outputs.
+func (this *TargetDefinition) runCommand(workingDir string) error {
+	commandString, err := this.Compiler.expandFindReferences(this.Command)
+
+	if err != nil {
+		return err
+	}
+
+	action := &ActionDefinition{
+		Name:  this.Name,
+		Shell: this.Shell,
+	}
+
+	return executeCommand(action.shellCommand(commandString), workingDir)
+}
+
+func (this *TargetDefinition) isSharedLibrary() bool {
+	return isSharedLibrary(this.Output) || filepath.Ext(this.Output) == ".dll"
+}
+
+func (this *TargetDefinition) compilesOnly() bool {
+	if this.Compiler.Object != nil {
+		return slices.Contains(this.Compiler.Flags, this.Compiler.Object.CompileOnlyFlag)
+	}
+
+	return slices.Contains(this.Compiler.Flags, "-c")
+}
+
+// The language a single compiler compiles the target in. C++ drivers like g++ compile
+// C sources as C++ as well, other drivers pick the language by the source extensions.
+func (this *TargetDefinition) language() string {
+	if this.Compiler.Object.defaultLanguage() == LANGUAGE_CXX {
+		return LANGUAGE_CXX
+	}
+
+	for _, source := range this.Sources {
+		if language, err := sourceLanguage(source.Path); err == nil && language == LANGUAGE_CXX {
+			return LANGUAGE_CXX
+		}
+	}
+
+	return LANGUAGE_C
 }
 
 func (this *TargetDefinition) mergeHookRefs(targetIndex int, definitionContext *DefinitionContext) error {
@@ -60,7 +139,7 @@ func (this *TargetDefinition) executeHooks(step string, workingDir string) error
 				}
 
 				fmt.Printf("%s...\n", message)
-				ok, err := targetHook.Actions[index].execute(workingDir)
+				ok, err := targetHook.Actions[index].execute(workingDir, &this.Compiler)
 
 				if err != nil {
 					return fmt.Errorf("ERROR: Could not execute hook for step `%s`: %s\n", step, err.Error())
@@ -128,6 +207,30 @@ func (this *TargetDefinition) fieldStringArrayValue(
 	return result, nil
 }
 
+func (this *TargetDefinition) fieldValues(fieldName string, definitionContext *DefinitionContext) ([]string, error) {
+	field := this.findField(fieldName)
+
+	if field == nil {
+		return nil, fmt.Errorf("Could not find field `%s`", fieldName)
+	}
+
+	if _, ok := field.Value().(string); ok {
+		value, err := this.fieldStringValue(fieldName, definitionContext)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return []string{value}, nil
+	}
+
+	if _, ok := field.Value().([]string); ok {
+		return this.fieldStringArrayValue(fieldName, definitionContext)
+	}
+
+	return nil, fmt.Errorf("Field `%s` cannot be referenced", fieldName)
+}
+
 func (this *TargetDefinition) dependencyGraph(index int, targetDefinitions *[]TargetDefinition) []int {
 	dependencyGraph := []int{index}
 
@@ -3,10 +3,10 @@ package gmakec
 import (
 	"os"
 	"path/filepath"
-	"runtime"
 	"strings"
 
 	"github.com/yargevad/filepathx"
+	"golang.org/x/exp/slices"
 )
 
 type TargetGroup struct {
@@ -26,46 +26,119 @@ func (this *TargetGroup) configure(
 			return nil, err
 		}
 
+		// may prepare the environment of the compiler, so find results are only available to later steps
 		if err := targetDef.executeHooks("pre-configure", definitionContext.DefinitionPath); err != nil {
 			return nil, err
 		}
 
-		compilerDef, err := targetDef.Compiler.sanitize(&definitionContext.Definition.Compilers)
+		compilerDef := &targetDef.Compiler
+		var languages map[string]*CompilerDefinition
+		var err error
 
-		if err != nil {
+		if targetDef.Languages.defined() {
+			languages, err = targetDef.Languages.sanitize(definitionContext)
+
+			if err != nil {
+				return nil, err
+			}
+
+			// replaced by the link driver as soon as the sources are known
+			compilerDef = primaryLanguageCompiler(languages)
+			targetDef.Compiler = *compilerDef
+		} else if !targetDef.isCommand() || isCompilerDefined(compilerDef) {
+			// command targets only need a compiler to reference its find results
+			compilerDef, err = targetDef.Compiler.sanitize(definitionContext)
+
+			if err != nil {
+				return nil, err
+			}
+
+			// the language is taken from the sources, the compiler driver is needed as a fallback
+			targetDef.Compiler = *compilerDef
+			targetDef.Compiler.Flags = append(compilerDef.Flags, compilerDef.supportedFlags(targetDef.language())...)
+		}
+
+		if targetDef.Compiler.Object != nil {
+			if err := targetDef.applyStandards(languages); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := targetDef.runChecks(definitionContext, languages); err != nil {
 			return nil, err
 		}
 
+		if targetDef.Compiler.Object != nil {
+			targetDef.applyWarnings(languages)
+			targetDef.applyProfile(definitionContext.Definition.Profile, languages)
+		}
+
+		variables := compilerDef.variables()
+
+		for key, value := range targetDef.checkVariables() {
+			variables[key] = value
+		}
+
 		for _, configureFile := range targetDef.ConfigureFiles {
-			if err := configureFile.Execute(definitionContext); err != nil {
+			if err := configureFile.Execute(definitionContext, variables); err != nil {
 				return nil, err
 			}
 		}
 
 		targetDefCopy := targetDef
-		targetDefCopy.Compiler = *compilerDef
 
 		target := Target{
 			Definition: &targetDefCopy,
 			Index:      targetIndex,
+			Languages:  languages,
 		}
 
+		if targetDef.isCommand() {
+			target.Sources, err = globPaths(targetDef.Inputs)
+
+			if err != nil {
+				return nil, err
+			}
+
+			targets = append(targets, target)
+
+			if err = targetDef.executeHooks("post-configure", definitionContext.DefinitionPath); err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		target.Flags, target.Libraries = compilerDef.findFlags()
+
 		for _, define := range targetDef.Defines {
 			target.Defines = append(target.Defines, compilerDef.Object.DefineFlag)
 			target.Defines = append(target.Defines, define)
 		}
 
+		target.Defines = append(target.Defines, targetDef.checkDefines()...)
+		target.Defines = append(target.Defines, definitionContext.Definition.Profile.defines(compilerDef.Object)...)
+
 		for _, include := range targetDef.Includes {
 			includeStrings := []string{}
 
 			if strings.Contains(include, ":") {
-				refStringArrayValue, err := findRefTargetStringArrayValue(include, &targetDef, definitionContexts)
+				refValues, err := findRefTargetValues(include, definitionContexts)
 
 				if err != nil {
 					return nil, err
 				}
 
-				includeStrings = append(includeStrings, refStringArrayValue...)
+				for _, refValue := range refValues {
+					// e.g. generated headers, which might not exist yet
+					if !isDirectory(refValue) && len(filepath.Ext(refValue)) > 0 {
+						refValue = filepath.Dir(refValue)
+					}
+
+					if !slices.Contains(includeStrings, refValue) {
+						includeStrings = append(includeStrings, refValue)
+					}
+				}
 			} else {
 				includeStrings = append(includeStrings, include)
 			}
@@ -100,6 +173,24 @@ func (this *TargetGroup) configure(
 
 				if strings.Contains(linkPath, ":") {
 					linkPath, err = findRefTargetStringValue(linkPath, &targetDef, definitionContexts)
+
+					if err != nil {
+						return nil, err
+					}
+
+					// shared libraries built by gmakec are not on the default library path
+					if !targetDef.RPath.SkipBuild && isSharedLibrary(linkPath) {
+						outputPath := filepath.Join(definitionContext.DefinitionPath, targetDef.Output)
+						rpath, err := buildTreeRPath(outputPath, linkPath)
+
+						if err != nil {
+							return nil, err
+						}
+
+						if !slices.Contains(target.RPaths, rpath) {
+							target.RPaths = append(target.RPaths, rpath)
+						}
+					}
 				}
 
 				target.Links = append(target.Links, filepath.Dir(linkPath))
@@ -109,7 +200,7 @@ func (this *TargetGroup) configure(
 		}
 
 		for _, source := range targetDef.Sources {
-			if len(source.Platform) > 0 && runtime.GOOS != source.Platform {
+			if len(source.Platform) > 0 && targetPlatform() != source.Platform {
 				continue
 			}
 
@@ -122,18 +213,35 @@ func (this *TargetGroup) configure(
 
 				target.Sources = append(target.Sources, globbed...)
 			} else if strings.Contains(source.Path, ":") {
-				refStringValue, err := findRefTargetStringValue(source.Path, &targetDef, definitionContexts)
+				refValues, err := findRefTargetValues(source.Path, definitionContexts)
 
 				if err != nil {
 					return nil, err
 				}
 
-				target.Sources = append(target.Sources, refStringValue)
+				for _, refValue := range refValues {
+					// e.g. headers among the outputs of command targets are no translation units
+					if !slices.Contains(headerExtensions, filepath.Ext(refValue)) {
+						target.Sources = append(target.Sources, refValue)
+					}
+				}
 			} else {
 				target.Sources = append(target.Sources, source.Path)
 			}
 		}
 
+		target.applyRules(&definitionContext.Definition.Rules)
+
+		if err = target.applyUnity(definitionContext.DefinitionPath); err != nil {
+			return nil, err
+		}
+
+		if target.isMixed() {
+			if err := target.selectLinkDriver(); err != nil {
+				return nil, err
+			}
+		}
+
 		targets = append(targets, target)
 
 		if err = targetDef.executeHooks("post-configure", definitionContext.DefinitionPath); err != nil {
@@ -0,0 +1,283 @@
+package gmakec
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DEFAULT_TEST_TIMEOUT time.Duration = 60 * time.Second
+
+// how long to wait for children of a killed test which keep its output open
+const TEST_WAIT_DELAY time.Duration = time.Second
+
+const (
+	TEST_PASS    string = "PASS"
+	TEST_FAIL    string = "FAIL"
+	TEST_TIMEOUT string = "TIMEOUT"
+)
+
+type TestDefinition struct {
+	Name        string   `yaml:"name"`
+	Target      string   `yaml:"target"`
+	Args        []string `yaml:"args"`
+	Environment []string `yaml:"environment"`
+	WorkingDir  string   `yaml:"working_dir"`
+	Timeout     string   `yaml:"timeout"`
+}
+
+type TestCase struct {
+	Name        string
+	Executable  string
+	Args        []string
+	Environment []string
+	WorkingDir  string
+	Timeout     time.Duration
+}
+
+type TestResult struct {
+	Name     string
+	Status   string
+	Duration time.Duration
+	Output   string
+	Message  string
+}
+
+func (this *TestDefinition) testCase(
+	definitionContext *DefinitionContext, definitionContexts *[]*DefinitionContext,
+) (*TestCase, error) {
+	if len(this.Target) == 0 {
+		return nil, fmt.Errorf("Test `%s` needs to have the field `target` set!", this.Name)
+	}
+
+	refContext, refTarget := findRefTarget(this.Target, definitionContexts)
+
+	if refContext == nil || refTarget == nil {
+		return nil, fmt.Errorf("Could not find target `%s` of test `%s`!", this.Target, this.Name)
+	}
+
+	executable, err := filepath.Abs(filepath.Join(refContext.DefinitionPath, refTarget.Output))
+
+	if err != nil {
+		return nil, err
+	}
+
+	workingDir := definitionContext.DefinitionPath
+
+	if len(this.WorkingDir) > 0 {
+		workingDir = filepath.Join(workingDir, this.WorkingDir)
+	}
+
+	timeout := DEFAULT_TEST_TIMEOUT
+
+	if len(this.Timeout) > 0 {
+		timeout, err = time.ParseDuration(this.Timeout)
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid timeout `%s` of test `%s`: %s", this.Timeout, this.Name, err.Error())
+		}
+	}
+
+	name := this.Name
+
+	if len(name) == 0 {
+		name = this.Target
+	}
+
+	return &TestCase{
+		Name:        name,
+		Executable:  executable,
+		Args:        this.Args,
+		Environment: this.Environment,
+		WorkingDir:  workingDir,
+		Timeout:     timeout,
+	}, nil
+}
+
+func (this *TestCase) matches(filter string) bool {
+	if len(filter) == 0 {
+		return true
+	}
+
+	if matched, err := filepath.Match(filter, this.Name); err == nil && matched {
+		return true
+	}
+
+	return strings.Contains(this.Name, filter)
+}
+
+func (this *TestCase) run() TestResult {
+	ctx, cancel := context.WithTimeout(context.Background(), this.Timeout)
+	defer cancel()
+
+	output := bytes.Buffer{}
+	command := exec.CommandContext(ctx, this.Executable, this.Args...)
+	command.Dir = this.WorkingDir
+	command.Stdout = &output
+	command.Stderr = &output
+	command.WaitDelay = TEST_WAIT_DELAY
+	command.Env = os.Environ()
+
+	for _, environmentVariable := range this.Environment {
+		command.Env = append(command.Env, os.ExpandEnv(environmentVariable))
+	}
+
+	start := time.Now()
+	err := command.Run()
+
+	result := TestResult{
+		Name:     this.Name,
+		Status:   TEST_PASS,
+		Duration: time.Since(start),
+		Output:   output.String(),
+	}
+
+	var exitError *exec.ExitError
+
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		result.Status = TEST_TIMEOUT
+		result.Message = fmt.Sprintf("timed out after %s", this.Timeout)
+	case errors.As(err, &exitError):
+		result.Status = TEST_FAIL
+		result.Message = fmt.Sprintf("exit code %d", exitError.ExitCode())
+	case err != nil:
+		result.Status = TEST_FAIL
+		result.Message = err.Error()
+	}
+
+	return result
+}
+
+// Runs the test cases in parallel, with at most `jobs` at a time.
+func RunTests(testCases []TestCase, jobs int) []TestResult {
+	results := make([]TestResult, len(testCases))
+	semaphore := make(chan struct{}, max(jobs, 1))
+	var wg sync.WaitGroup
+
+	for index := range testCases {
+		wg.Add(1)
+
+		go func(index int) {
+			defer wg.Done()
+
+			semaphore <- struct{}{}
+			results[index] = testCases[index].run()
+			<-semaphore
+
+			printTestResult(&results[index])
+		}(index)
+	}
+
+	wg.Wait()
+	return results
+}
+
+var printMutex sync.Mutex
+
+func printTestResult(result *TestResult) {
+	printMutex.Lock()
+	defer printMutex.Unlock()
+
+	if result.Status == TEST_PASS {
+		fmt.Printf("[test] %s %s (%.2fs)\n", result.Status, result.Name, result.Duration.Seconds())
+		return
+	}
+
+	fmt.Printf("[test] %s %s (%.2fs, %s)\n", result.Status, result.Name, result.Duration.Seconds(), result.Message)
+
+	for _, line := range strings.Split(strings.TrimRight(result.Output, "\n"), "\n") {
+		fmt.Printf("[test]     %s\n", line)
+	}
+}
+
+// Prints a summary and returns an error if any test did not pass.
+func SummarizeTests(results []TestResult) error {
+	counts := map[string]int{}
+
+	for _, result := range results {
+		counts[result.Status]++
+	}
+
+	fmt.Printf(
+		"[test] %d tests, %d passed, %d failed, %d timed out\n",
+		len(results), counts[TEST_PASS], counts[TEST_FAIL], counts[TEST_TIMEOUT],
+	)
+
+	if counts[TEST_PASS] != len(results) {
+		return fmt.Errorf("%d of %d tests did not pass!", len(results)-counts[TEST_PASS], len(results))
+	}
+
+	return nil
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Error     *junitFailure `xml:"error,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitTestSuite struct {
+	XMLName   xml.Name        `xml:"testsuite"`
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Time      string          `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+func WriteJUnitReport(path string, suiteName string, results []TestResult) error {
+	suite := junitTestSuite{
+		Name:  suiteName,
+		Tests: len(results),
+	}
+
+	totalDuration := time.Duration(0)
+
+	for _, result := range results {
+		testCase := junitTestCase{
+			Name:      result.Name,
+			ClassName: suiteName,
+			Time:      fmt.Sprintf("%.3f", result.Duration.Seconds()),
+			SystemOut: result.Output,
+		}
+
+		switch result.Status {
+		case TEST_FAIL:
+			suite.Failures++
+			testCase.Failure = &junitFailure{Message: result.Message, Text: result.Output}
+		case TEST_TIMEOUT:
+			suite.Errors++
+			testCase.Error = &junitFailure{Message: result.Message, Text: result.Output}
+		}
+
+		totalDuration += result.Duration
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+
+	suite.Time = fmt.Sprintf("%.3f", totalDuration.Seconds())
+	contents, err := xml.MarshalIndent(suite, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append([]byte(xml.Header), append(contents, '\n')...), 0644)
+}
@@ -0,0 +1,214 @@
+package gmakec
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// A cross compilation toolchain overriding the host tools for all targets.
+type Toolchain struct {
+	Name    string `yaml:"name"`
+	Prefix  string `yaml:"prefix"`
+	Sysroot string `yaml:"sysroot"`
+	Target  string `yaml:"target"`
+	System  string `yaml:"system"`
+	Ar      string `yaml:"ar"`
+	Objcopy string `yaml:"objcopy"`
+	Strip   string `yaml:"strip"`
+
+	// replaces programs by others, e.g. cl by clang-cl
+	Programs     map[string]string `yaml:"programs"`
+	Flags        []string          `yaml:"flags"`
+	ProgramPaths []string          `yaml:"program_paths"`
+	IncludePaths []string          `yaml:"include_paths"`
+	LibraryPaths []string          `yaml:"library_paths"`
+}
+
+var toolchain *Toolchain
+
+// Ordered from specific to generic, e.g. aarch64-linux-android is android, not linux.
+var triplePlatforms = [][2]string{
+	{"android", "android"},
+	{"mingw", "windows"},
+	{"windows", "windows"},
+	{"darwin", "darwin"},
+	{"apple", "darwin"},
+	{"freebsd", "freebsd"},
+	{"netbsd", "netbsd"},
+	{"openbsd", "openbsd"},
+	{"linux", "linux"},
+}
+
+func LoadToolchain(path string) error {
+	content, err := os.ReadFile(path)
+
+	if err != nil {
+		return err
+	}
+
+	toolchainDef := Toolchain{}
+
+	if err = yaml.Unmarshal(content, &toolchainDef); err != nil {
+		return fmt.Errorf("Could not parse toolchain `%s`: %s", path, err.Error())
+	}
+
+	// paths are relative to the toolchain file
+	toolchainDir, err := filepath.Abs(filepath.Dir(path))
+
+	if err != nil {
+		return err
+	}
+
+	toolchainDef.Sysroot = toolchainPath(toolchainDir, toolchainDef.Sysroot)
+
+	for _, paths := range [][]string{toolchainDef.ProgramPaths, toolchainDef.IncludePaths, toolchainDef.LibraryPaths} {
+		for index := range paths {
+			paths[index] = toolchainPath(toolchainDir, paths[index])
+		}
+	}
+
+	toolchain = &toolchainDef
+	return nil
+}
+
+func toolchainPath(toolchainDir string, path string) string {
+	path = expandUserPath(path)
+
+	if len(path) == 0 || filepath.IsAbs(path) {
+		return path
+	}
+
+	return filepath.Join(toolchainDir, path)
+}
+
+// The OS targets are built for, used for `platform` filtering.
+func targetPlatform() string {
+	if toolchain == nil {
+		return runtime.GOOS
+	}
+
+	if len(toolchain.System) > 0 {
+		return toolchain.System
+	}
+
+	if len(toolchain.Target) == 0 {
+		return runtime.GOOS
+	}
+
+	components := strings.Split(toolchain.Target, "-")
+
+	for _, triplePlatform := range triplePlatforms {
+		for _, component := range components {
+			if strings.HasPrefix(component, triplePlatform[0]) {
+				return triplePlatform[1]
+			}
+		}
+	}
+
+	// bare metal targets like arm-none-eabi
+	return "none"
+}
+
+// Resolves tools like `gcc` or `ar` to the prefixed ones of the toolchain, e.g. `arm-none-eabi-gcc`.
+func toolchainProgram(program string) string {
+	if toolchain == nil || filepath.IsAbs(program) || strings.ContainsRune(program, filepath.Separator) {
+		return program
+	}
+
+	if replacement, ok := toolchain.Programs[program]; ok {
+		program = replacement
+	}
+
+	program = fmt.Sprintf("%s%s", toolchain.Prefix, program)
+
+	for _, programPath := range toolchain.ProgramPaths {
+		path := filepath.Join(programPath, program)
+
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+
+	return program
+}
+
+// System directories inside the sysroot of the toolchain.
+func sysrootPaths(paths ...string) []string {
+	if toolchain == nil {
+		return paths
+	}
+
+	sysrootPaths := []string{}
+
+	for _, path := range paths {
+		sysrootPaths = append(sysrootPaths, filepath.Join(toolchain.Sysroot, path))
+	}
+
+	return sysrootPaths
+}
+
+func toolchainTool(tool string, fallback string) string {
+	if len(tool) > 0 {
+		return tool
+	}
+
+	return toolchainProgram(fallback)
+}
+
+func stripProgram() string {
+	if toolchain == nil {
+		return "strip"
+	}
+
+	return toolchainTool(toolchain.Strip, "strip")
+}
+
+// Flags every compiler of the toolchain gets, spelled for the compiler family.
+func (this *Toolchain) compilerFlags(compiler *Compiler) []string {
+	flags := []string{}
+
+	if len(this.Target) > 0 && len(compiler.TargetFlag) > 0 {
+		flags = append(flags, fmt.Sprintf("%s%s", compiler.TargetFlag, this.Target))
+	}
+
+	if len(this.Sysroot) > 0 && len(compiler.SysrootFlag) > 0 {
+		flags = append(flags, fmt.Sprintf("%s%s", compiler.SysrootFlag, this.Sysroot))
+	}
+
+	flags = append(flags, this.Flags...)
+
+	for _, includePath := range this.IncludePaths {
+		flags = append(flags, compiler.IncludeSearchFlag, includePath)
+	}
+
+	for _, libraryPath := range this.LibraryPaths {
+		flags = append(flags, fmt.Sprintf("%s%s", compiler.LinkSearchFlag, libraryPath))
+	}
+
+	return flags
+}
+
+// Variables like TOOLCHAIN_SYSROOT, usable in configure files and action environments.
+func toolchainVariables() map[string]string {
+	variables := map[string]string{
+		"TARGET_PLATFORM": targetPlatform(),
+	}
+
+	if toolchain == nil {
+		return variables
+	}
+
+	variables["TOOLCHAIN_NAME"] = toolchain.Name
+	variables["TOOLCHAIN_PREFIX"] = toolchain.Prefix
+	variables["TOOLCHAIN_SYSROOT"] = toolchain.Sysroot
+	variables["TOOLCHAIN_TARGET"] = toolchain.Target
+	variables["TOOLCHAIN_AR"] = toolchainTool(toolchain.Ar, "ar")
+	variables["TOOLCHAIN_OBJCOPY"] = toolchainTool(toolchain.Objcopy, "objcopy")
+	variables["TOOLCHAIN_STRIP"] = stripProgram()
+	return variables
+}
@@ -0,0 +1,140 @@
+package gmakec
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/exp/slices"
+)
+
+const DEFAULT_UNITY_BATCH_SIZE int = 8
+
+var unityExtensions = map[string]string{
+	LANGUAGE_C:   ".c",
+	LANGUAGE_CXX: ".cpp",
+}
+
+// Overrides the unity setting of all targets when set from the command line.
+var unityBuilds *bool
+
+func SetUnityBuilds(enabled bool) {
+	unityBuilds = &enabled
+}
+
+type UnitySource struct {
+	Output  string
+	Sources []string
+}
+
+func (this *TargetDefinition) isUnity() bool {
+	if unityBuilds != nil {
+		return *unityBuilds
+	}
+
+	return this.Unity
+}
+
+func (this *TargetDefinition) unityBatchSize() int {
+	if this.UnityBatchSize > 0 {
+		return this.UnityBatchSize
+	}
+
+	return DEFAULT_UNITY_BATCH_SIZE
+}
+
+func (this *UnitySource) content() []byte {
+	var content bytes.Buffer
+	content.WriteString("/* generated by gmakec, do not edit */\n")
+
+	for _, source := range this.Sources {
+		includePath, err := filepath.Rel(filepath.Dir(this.Output), source)
+
+		if err != nil {
+			includePath, _ = filepath.Abs(source)
+		}
+
+		content.WriteString(fmt.Sprintf("#include \"%s\"\n", filepath.ToSlash(includePath)))
+	}
+
+	return content.Bytes()
+}
+
+// Only writes the file if its content changed, so the sources including it don't get rebuilt.
+func (this *UnitySource) write(workingDir string) error {
+	content := this.content()
+	outputPath := filepath.Join(workingDir, this.Output)
+	existing, err := os.ReadFile(outputPath)
+
+	if err == nil && bytes.Equal(existing, content) {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(outputPath), os.ModePerm); err != nil {
+		return err
+	}
+
+	return os.WriteFile(outputPath, content, 0644)
+}
+
+// Replaces the C and C++ sources by combined translation units including a batch of them each.
+func (this *Target) applyUnity(workingDir string) error {
+	if !this.Definition.isUnity() {
+		return nil
+	}
+
+	outputDir := filepath.Join(filepath.Dir(this.Definition.Output), "unity", filepath.Base(this.Definition.Output))
+	batchSize := this.Definition.unityBatchSize()
+	batches := map[string][]string{}
+	sources := []string{}
+
+	for _, source := range this.Sources {
+		language, err := sourceLanguage(source)
+
+		if _, ok := unityExtensions[language]; err != nil || !ok {
+			sources = append(sources, source)
+			continue
+		}
+
+		batches[language] = append(batches[language], source)
+	}
+
+	for _, language := range []string{LANGUAGE_C, LANGUAGE_CXX} {
+		for index := 0; index*batchSize < len(batches[language]); index++ {
+			batch := batches[language][index*batchSize:]
+
+			if len(batch) > batchSize {
+				batch = batch[:batchSize]
+			}
+
+			unitySource := UnitySource{
+				Output:  filepath.Join(outputDir, fmt.Sprintf("unity_%s_%d%s", language, index, unityExtensions[language])),
+				Sources: batch,
+			}
+
+			if err := unitySource.write(workingDir); err != nil {
+				return fmt.Errorf("Could not write unity source `%s`: %s", unitySource.Output, err.Error())
+			}
+
+			this.Unity = append(this.Unity, unitySource)
+			sources = append(sources, unitySource.Output)
+		}
+	}
+
+	this.Sources = sources
+	return nil
+}
+
+// The sources which a unity source includes, or the source itself.
+func (this *Target) includedSources(source string) []string {
+	index := slices.IndexFunc(this.Unity, func(unitySource UnitySource) bool {
+		return unitySource.Output == source
+	})
+
+	if index < 0 {
+		return []string{source}
+	}
+
+	return append([]string{source}, this.Unity[index].Sources...)
+}
@@ -0,0 +1,114 @@
+package gmakec
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var versionOperators = []string{">=", "<=", "!=", "==", "=", ">", "<"}
+
+func splitVersionSegments(version string) []string {
+	return strings.FieldsFunc(version, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Compares versions segment by segment, numerically where possible.
+// Returns -1, 0 or 1 like strings.Compare.
+func compareVersions(left string, right string) int {
+	leftSegments := splitVersionSegments(left)
+	rightSegments := splitVersionSegments(right)
+
+	for index := 0; index < len(leftSegments) && index < len(rightSegments); index++ {
+		leftNumber, leftErr := strconv.Atoi(leftSegments[index])
+		rightNumber, rightErr := strconv.Atoi(rightSegments[index])
+
+		switch {
+		case leftErr == nil && rightErr == nil:
+			if leftNumber != rightNumber {
+				if leftNumber < rightNumber {
+					return -1
+				}
+
+				return 1
+			}
+		case leftErr == nil:
+			return 1
+		case rightErr == nil:
+			return -1
+		default:
+			if result := strings.Compare(leftSegments[index], rightSegments[index]); result != 0 {
+				return result
+			}
+		}
+	}
+
+	switch {
+	case len(leftSegments) < len(rightSegments):
+		return -1
+	case len(leftSegments) > len(rightSegments):
+		return 1
+	}
+
+	return 0
+}
+
+func parseVersionConstraint(constraint string) (string, string, error) {
+	constraint = strings.TrimSpace(constraint)
+
+	for _, operator := range versionOperators {
+		if strings.HasPrefix(constraint, operator) {
+			version := strings.TrimSpace(strings.TrimPrefix(constraint, operator))
+
+			if len(version) == 0 {
+				return "", "", fmt.Errorf("Version constraint `%s` is missing a version!", constraint)
+			}
+
+			return operator, version, nil
+		}
+	}
+
+	if len(constraint) == 0 {
+		return "", "", fmt.Errorf("Empty version constraint!")
+	}
+
+	// a plain version means an exact match
+	return "=", constraint, nil
+}
+
+// Checks a version against constraints like `>=3.8` or `>= 1.2, < 2`.
+func versionSatisfies(version string, constraints string) (bool, error) {
+	for _, constraint := range strings.Split(constraints, ",") {
+		operator, constraintVersion, err := parseVersionConstraint(constraint)
+
+		if err != nil {
+			return false, err
+		}
+
+		result := compareVersions(version, constraintVersion)
+		satisfied := false
+
+		switch operator {
+		case ">=":
+			satisfied = result >= 0
+		case "<=":
+			satisfied = result <= 0
+		case "!=":
+			satisfied = result != 0
+		case "==", "=":
+			satisfied = result == 0
+		case ">":
+			satisfied = result > 0
+		case "<":
+			satisfied = result < 0
+		}
+
+		if !satisfied {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}